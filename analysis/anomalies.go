@@ -0,0 +1,191 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// AnomalyMethod selects how a value is scored against its rolling baseline.
+type AnomalyMethod string
+
+const (
+	// MethodZScore scores by standard deviations above the baseline mean.
+	MethodZScore AnomalyMethod = "zscore"
+	// MethodMAD scores by the modified z-score, using the median absolute
+	// deviation, which is robust against earlier spikes in the baseline.
+	MethodMAD AnomalyMethod = "mad"
+)
+
+// Metric names reported on anomalies
+const (
+	MetricTotalCPU  = "Total CPU"
+	MetricLoad1     = "Load 1m"
+	MetricIOWait    = "IOWait"
+	MetricSteal     = "Steal"
+	MetricThreadCPU = "Thread CPU"
+)
+
+// AnomalyConfig tunes anomaly detection. Zero fields fall back to defaults.
+type AnomalyConfig struct {
+	Method    AnomalyMethod
+	Threshold float64 // minimum score to flag, default 3.5
+	Window    int     // snapshots in the rolling baseline, default 10
+	// MinThreadCPU ignores thread spikes below this %CPU, default 25
+	MinThreadCPU float64
+}
+
+// ParseAnomalyMethod validates an anomaly scoring method name.
+func ParseAnomalyMethod(s string) (AnomalyMethod, error) {
+	switch m := AnomalyMethod(s); m {
+	case MethodZScore, MethodMAD:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown anomaly method %q (want zscore or mad)", s)
+	}
+}
+
+func (c AnomalyConfig) withDefaults() AnomalyConfig {
+	if c.Method == "" {
+		c.Method = MethodMAD
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 3.5
+	}
+	if c.Window <= 1 {
+		c.Window = 10
+	}
+	if c.MinThreadCPU <= 0 {
+		c.MinThreadCPU = 25
+	}
+	return c
+}
+
+// Anomaly is a run of consecutive snapshots where a metric rose well above
+// its rolling baseline.
+type Anomaly struct {
+	Metric   string
+	TID      int    // set for MetricThreadCPU
+	Command  string // set for MetricThreadCPU
+	Start    int    // first anomalous snapshot index
+	End      int    // last anomalous snapshot index
+	Peak     int    // snapshot index of the highest score
+	Value    float64
+	Baseline float64
+	Score    float64
+}
+
+// minSpread floors the baseline spread per metric so a perfectly flat
+// baseline does not turn every small wobble into an infinite score.
+var minSpread = map[string]float64{
+	MetricTotalCPU:  2,
+	MetricLoad1:     0.25,
+	MetricIOWait:    1,
+	MetricSteal:     1,
+	MetricThreadCPU: 5,
+}
+
+// DetectAnomalies scans total CPU, load, iowait, steal and every thread's CPU
+// for values that jump above their rolling baseline. Results are ordered by
+// start time.
+func DetectAnomalies(data parser.ReportData, cfg AnomalyConfig) []Anomaly {
+	cfg = cfg.withDefaults()
+	metric := func(get func(parser.Metadata) float64) []float64 {
+		values := make([]float64, len(data.Snapshots))
+		for i, s := range data.Snapshots {
+			values[i] = get(s.Metadata)
+		}
+		return values
+	}
+
+	var anomalies []Anomaly
+	for _, m := range []struct {
+		name   string
+		values []float64
+	}{
+		{MetricTotalCPU, metric(func(m parser.Metadata) float64 { return m.CPUUser + m.CPUSystem })},
+		{MetricLoad1, metric(func(m parser.Metadata) float64 { return m.LoadAvg1 })},
+		{MetricIOWait, metric(func(m parser.Metadata) float64 { return m.CPUWait })},
+		{MetricSteal, metric(func(m parser.Metadata) float64 { return m.CPUSteal })},
+	} {
+		anomalies = append(anomalies, scanSeries(m.name, m.values, cfg, 0)...)
+	}
+	for _, t := range Threads(data) {
+		for _, a := range scanSeries(MetricThreadCPU, t.CPU, cfg, cfg.MinThreadCPU) {
+			a.TID = t.TID
+			a.Command = t.Command
+			anomalies = append(anomalies, a)
+		}
+	}
+
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return anomalies[i].Start < anomalies[j].Start
+	})
+	return anomalies
+}
+
+// scanSeries flags values scoring above the threshold against the preceding
+// window, merging consecutive hits into one anomaly.
+func scanSeries(name string, values []float64, cfg AnomalyConfig, minValue float64) []Anomaly {
+	minBaseline := cfg.Window / 2
+	if minBaseline < 3 {
+		minBaseline = 3
+	}
+	var out []Anomaly
+	var current *Anomaly
+	for i, v := range values {
+		start := i - cfg.Window
+		if start < 0 {
+			start = 0
+		}
+		baseline := values[start:i]
+		flagged := false
+		var center, score float64
+		if len(baseline) >= minBaseline && v >= minValue {
+			center, score = scoreValue(v, baseline, cfg.Method, minSpread[name])
+			flagged = score >= cfg.Threshold
+		}
+		if !flagged {
+			if current != nil {
+				out = append(out, *current)
+				current = nil
+			}
+			continue
+		}
+		if current == nil {
+			current = &Anomaly{Metric: name, Start: i}
+		}
+		current.End = i
+		if score > current.Score {
+			current.Peak = i
+			current.Value = v
+			current.Baseline = center
+			current.Score = score
+		}
+	}
+	if current != nil {
+		out = append(out, *current)
+	}
+	return out
+}
+
+// scoreValue returns the baseline center and how far above it v lies.
+func scoreValue(v float64, baseline []float64, method AnomalyMethod, floor float64) (center, score float64) {
+	if method == MethodZScore {
+		center = Mean(baseline)
+		spread := math.Max(StdDev(baseline), floor)
+		return center, (v - center) / spread
+	}
+	sorted := append([]float64(nil), baseline...)
+	sort.Float64s(sorted)
+	center = Percentile(sorted, 50)
+	deviations := make([]float64, len(baseline))
+	for i, b := range baseline {
+		deviations[i] = math.Abs(b - center)
+	}
+	sort.Float64s(deviations)
+	mad := math.Max(Percentile(deviations, 50), floor)
+	return center, 0.6745 * (v - center) / mad
+}
@@ -0,0 +1,63 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// spikeData returns twenty quiet snapshots with an iowait and thread spike
+// at snapshots 14 and 15.
+func spikeData() parser.ReportData {
+	return capture(every(20, 2), func(i int, s *parser.Snapshot) {
+		wait := 1.0 + float64(i%2)*0.5
+		cpu := 10.0 + float64(i%3)
+		if i == 14 || i == 15 {
+			wait = 40
+			cpu = 95
+		}
+		s.Processes = []parser.ProcessData{{PID: 7, Command: "worker", CPU: cpu}}
+		s.Metadata = parser.Metadata{CPUUser: 20, CPUSystem: 5, CPUWait: wait, LoadAvg1: 2}
+	})
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	for _, method := range []AnomalyMethod{MethodMAD, MethodZScore} {
+		anomalies := DetectAnomalies(spikeData(), AnomalyConfig{Method: method})
+		var wait, thread *Anomaly
+		for i := range anomalies {
+			switch anomalies[i].Metric {
+			case MetricIOWait:
+				wait = &anomalies[i]
+			case MetricThreadCPU:
+				thread = &anomalies[i]
+			default:
+				t.Errorf("%s: unexpected anomaly %+v", method, anomalies[i])
+			}
+		}
+		if wait == nil || wait.Start != 14 || wait.Value != 40 {
+			t.Errorf("%s: iowait spike not detected: %+v", method, wait)
+		}
+		if thread == nil || thread.TID != 7 || thread.Command != "worker" || thread.Start != 14 {
+			t.Errorf("%s: thread spike not detected: %+v", method, thread)
+		}
+	}
+}
+
+func TestDetectAnomalies_MergesConsecutiveSnapshots(t *testing.T) {
+	anomalies := DetectAnomalies(spikeData(), AnomalyConfig{Method: MethodMAD})
+	for _, a := range anomalies {
+		if a.Metric == MetricIOWait && a.End != 15 {
+			t.Errorf("expected the iowait anomaly to span snapshots 14-15, got %d-%d", a.Start, a.End)
+		}
+	}
+}
+
+func TestDetectAnomalies_FlatCapture(t *testing.T) {
+	flat := capture(every(20, 1), func(_ int, s *parser.Snapshot) {
+		s.Metadata = parser.Metadata{CPUUser: 50, LoadAvg1: 1}
+	})
+	if got := DetectAnomalies(flat, AnomalyConfig{}); len(got) != 0 {
+		t.Errorf("expected no anomalies in a flat capture, got %+v", got)
+	}
+}
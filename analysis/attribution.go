@@ -0,0 +1,100 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// AttributionBy selects what the CPU attribution stacks.
+type AttributionBy string
+
+const (
+	AttributeThreads AttributionBy = "threads"
+	AttributePools   AttributionBy = "pools"
+)
+
+// AttributionConfig tunes AttributeCPU.
+type AttributionConfig struct {
+	By AttributionBy
+	// TopN is how many threads or pools get their own band; the rest are
+	// summed into Other. Zero gives every thread or pool a band.
+	TopN int
+	// Rank orders threads; pools are always ordered by total CPU.
+	Rank RankMetric
+	// Pools groups threads into pools; nil uses DefaultNormalizer.
+	Pools *Normalizer
+	// Cores scales the host CPU to % of one core; zero leaves Host nil.
+	Cores int
+}
+
+// AttributionBand is one thread's or pool's %CPU per snapshot.
+type AttributionBand struct {
+	Name string
+	CPU  []float64
+}
+
+// Attribution divides the captured CPU among the busiest threads or pools so
+// the bands stack up to the listed threads' combined CPU.
+type Attribution struct {
+	Bands []AttributionBand // busiest first
+	Other []float64         // summed %CPU of the remaining threads or pools; nil when there are none
+	// Host is the host's user plus system CPU in % of one core, the unit of
+	// top's per-thread %CPU, so the gap above the bands is CPU used outside
+	// the capture. It is nil when the core count or the "%Cpu(s)" summary is
+	// missing.
+	Host []float64
+}
+
+// AttributeCPU stacks the captured CPU by thread or pool against the host's
+// busy CPU.
+func AttributeCPU(data parser.ReportData, cfg AttributionConfig) Attribution {
+	var a Attribution
+	if cfg.By == AttributePools {
+		pools := Pools(data, cfg.Pools)
+		n := cfg.TopN
+		if n <= 0 || n > len(pools) {
+			n = len(pools)
+		}
+		for _, p := range pools[:n] {
+			a.Bands = append(a.Bands, AttributionBand{Name: p.Name, CPU: p.CPU})
+		}
+		if n < len(pools) {
+			a.Other = make([]float64, len(data.Snapshots))
+			for _, p := range pools[n:] {
+				for i, v := range p.CPU {
+					a.Other[i] += v
+				}
+			}
+		}
+	} else {
+		top, other := TopThreads(data, cfg.TopN, cfg.Rank)
+		for _, t := range top {
+			a.Bands = append(a.Bands, AttributionBand{Name: fmt.Sprintf("%s (%d)", t.Command, t.TID), CPU: t.CPU})
+		}
+		a.Other = other
+	}
+	a.Host = hostCPU(data, cfg.Cores)
+	return a
+}
+
+// hostCPU returns the host's user plus system CPU per snapshot in % of one
+// core, or nil when the cores or the CPU summary are unknown.
+func hostCPU(data parser.ReportData, cores int) []float64 {
+	if cores <= 0 {
+		return nil
+	}
+	summarized := false
+	host := make([]float64, len(data.Snapshots))
+	for i, s := range data.Snapshots {
+		m := s.Metadata
+		if m.CPUUser+m.CPUSystem+m.CPUIdle+m.CPUWait+m.CPUSteal > 0 {
+			summarized = true
+		}
+		host[i] = (m.CPUUser + m.CPUSystem) * float64(cores)
+	}
+	if !summarized {
+		return nil
+	}
+	return host
+}
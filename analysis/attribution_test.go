@@ -0,0 +1,74 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func attributionCapture() parser.ReportData {
+	host := []parser.Metadata{
+		{CPUUser: 30, CPUSystem: 10, CPUIdle: 60},
+		{CPUUser: 20, CPUSystem: 5, CPUIdle: 75},
+	}
+	threads := listed(
+		[]parser.ProcessData{
+			{PID: 1, Command: "worker-1", CPU: 80},
+			{PID: 2, Command: "worker-2", CPU: 40},
+			{PID: 3, Command: "GC Thread#0", CPU: 10},
+		},
+		[]parser.ProcessData{
+			{PID: 1, Command: "worker-1", CPU: 60},
+			{PID: 2, Command: "worker-2", CPU: 20},
+			{PID: 3, Command: "GC Thread#0", CPU: 30},
+		},
+	)
+	return capture(every(2, 1), func(i int, s *parser.Snapshot) {
+		threads(i, s)
+		s.Metadata = host[i]
+	})
+}
+
+func TestAttributeCPU_Threads(t *testing.T) {
+	a := AttributeCPU(attributionCapture(), AttributionConfig{By: AttributeThreads, TopN: 1, Cores: 4})
+	want := []AttributionBand{{Name: "worker-1 (1)", CPU: []float64{80, 60}}}
+	if !reflect.DeepEqual(a.Bands, want) {
+		t.Errorf("expected bands %+v, got %+v", want, a.Bands)
+	}
+	if !reflect.DeepEqual(a.Other, []float64{50, 50}) {
+		t.Errorf("expected the other threads to sum to 50%%, got %v", a.Other)
+	}
+	// (user + system) × cores
+	if !reflect.DeepEqual(a.Host, []float64{160, 100}) {
+		t.Errorf("expected host CPU in %% of one core, got %v", a.Host)
+	}
+}
+
+func TestAttributeCPU_Pools(t *testing.T) {
+	a := AttributeCPU(attributionCapture(), AttributionConfig{By: AttributePools, TopN: 1})
+	if len(a.Bands) != 1 || a.Bands[0].Name != "worker" || !reflect.DeepEqual(a.Bands[0].CPU, []float64{120, 80}) {
+		t.Errorf("expected the worker pool band, got %+v", a.Bands)
+	}
+	if !reflect.DeepEqual(a.Other, []float64{10, 30}) {
+		t.Errorf("expected the GC pool in other, got %v", a.Other)
+	}
+	if a.Host != nil {
+		t.Errorf("expected no host CPU without a core count, got %v", a.Host)
+	}
+
+	a = AttributeCPU(attributionCapture(), AttributionConfig{By: AttributePools})
+	if len(a.Bands) != 2 || a.Other != nil {
+		t.Errorf("expected every pool to get a band, got %+v", a)
+	}
+}
+
+func TestHostCPU_NoSummary(t *testing.T) {
+	data := attributionCapture()
+	for i := range data.Snapshots {
+		data.Snapshots[i].Metadata = parser.Metadata{}
+	}
+	if host := hostCPU(data, 4); host != nil {
+		t.Errorf("expected no host CPU without a %%Cpu(s) summary, got %v", host)
+	}
+}
@@ -0,0 +1,187 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// profileVersion is bumped whenever the profile format changes incompatibly.
+const profileVersion = 1
+
+// Distribution is the spread of a metric over every baseline snapshot.
+type Distribution struct {
+	Samples int     `json:"samples"`
+	Mean    float64 `json:"mean"`
+	StdDev  float64 `json:"stddev"`
+	Min     float64 `json:"min"`
+	P5      float64 `json:"p5"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	Max     float64 `json:"max"`
+}
+
+func distribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return Distribution{
+		Samples: len(values),
+		Mean:    Mean(values),
+		StdDev:  StdDev(values),
+		Min:     sorted[0],
+		P5:      Percentile(sorted, 5),
+		P50:     Percentile(sorted, 50),
+		P95:     Percentile(sorted, 95),
+		Max:     sorted[len(sorted)-1],
+	}
+}
+
+// Profile is the normal behaviour learned from one or more known good
+// captures: the distribution of each system metric and of each pool's CPU.
+type Profile struct {
+	Version   int                     `json:"version"`
+	Captures  int                     `json:"captures"`
+	Snapshots int                     `json:"snapshots"`
+	Metrics   map[string]Distribution `json:"metrics"`
+	Pools     map[string]Distribution `json:"pools"`
+}
+
+// BuildProfile pools every snapshot of the captures into one profile. A pool
+// missing from a snapshot counts as 0% CPU there.
+func BuildProfile(captures []parser.ReportData, n *Normalizer) Profile {
+	p := Profile{
+		Version:  profileVersion,
+		Captures: len(captures),
+		Metrics:  make(map[string]Distribution),
+		Pools:    make(map[string]Distribution),
+	}
+	metrics := make(map[string][]float64)
+	pools := make(map[string][]float64)
+	for _, data := range captures {
+		offset := p.Snapshots
+		p.Snapshots += len(data.Snapshots)
+		for _, m := range SystemMetrics {
+			metrics[m.Name] = append(metrics[m.Name], m.Values(data)...)
+		}
+		for _, pool := range Pools(data, n) {
+			values := pools[pool.Name]
+			// pad snapshots of earlier captures that lacked this pool
+			values = append(values, make([]float64, offset-len(values))...)
+			pools[pool.Name] = append(values, pool.CPU...)
+		}
+	}
+	for name, values := range metrics {
+		p.Metrics[name] = distribution(values)
+	}
+	for name, values := range pools {
+		values = append(values, make([]float64, p.Snapshots-len(values))...)
+		p.Pools[name] = distribution(values)
+	}
+	return p
+}
+
+// WriteProfile encodes p as indented JSON.
+func WriteProfile(w io.Writer, p Profile) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return fmt.Errorf("encode baseline profile: %w", err)
+	}
+	return nil
+}
+
+// ReadProfile decodes a profile written by WriteProfile.
+func ReadProfile(r io.Reader) (Profile, error) {
+	var p Profile
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return Profile{}, fmt.Errorf("decode baseline profile: %w", err)
+	}
+	if p.Version != profileVersion {
+		return Profile{}, fmt.Errorf("unsupported baseline profile version %d (want %d)", p.Version, profileVersion)
+	}
+	return p, nil
+}
+
+// Deviation kinds
+const (
+	DeviationMetric = "metric"
+	DeviationPool   = "pool"
+)
+
+// Deviation scores how far a capture strays from its baseline for one metric
+// or pool.
+type Deviation struct {
+	Kind     string
+	Name     string
+	Baseline Distribution
+	Mean     float64 // mean over the scored capture
+	// Score is the capture's mean in baseline standard deviations from the
+	// baseline mean; positive when higher.
+	Score float64
+	// Outside is the share of snapshots outside the baseline's p5..p95 range.
+	Outside float64
+	// New is set for pools the baseline never saw, which are scored as if
+	// the baseline had them at 0% CPU.
+	New bool
+}
+
+// deviationFloor keeps a perfectly flat baseline from turning tiny changes
+// into huge scores, as a fraction of the baseline mean with an absolute
+// minimum of 1.
+func deviationFloor(d Distribution) float64 {
+	return math.Max(d.StdDev, math.Max(math.Abs(d.Mean)*0.05, 1))
+}
+
+// ScoreAgainst scores every system metric and pool of data against the
+// profile, largest deviation first. Pools in the baseline but missing from
+// data are scored as 0% CPU.
+func ScoreAgainst(data parser.ReportData, profile Profile, n *Normalizer) []Deviation {
+	var out []Deviation
+	score := func(kind, name string, values []float64, base Distribution, seen bool) {
+		d := Deviation{Kind: kind, Name: name, Baseline: base, Mean: Mean(values), New: !seen}
+		d.Score = (d.Mean - base.Mean) / deviationFloor(base)
+		outside := 0
+		for _, v := range values {
+			if v < base.P5 || v > base.P95 {
+				outside++
+			}
+		}
+		if len(values) > 0 {
+			d.Outside = float64(outside) / float64(len(values))
+		}
+		out = append(out, d)
+	}
+
+	for _, m := range SystemMetrics {
+		if base, ok := profile.Metrics[m.Name]; ok {
+			score(DeviationMetric, m.Name, m.Values(data), base, true)
+		}
+	}
+	current := make(map[string]bool)
+	for _, p := range Pools(data, n) {
+		current[p.Name] = true
+		base, ok := profile.Pools[p.Name]
+		score(DeviationPool, p.Name, p.CPU, base, ok)
+	}
+	for name, base := range profile.Pools {
+		if !current[name] {
+			score(DeviationPool, name, make([]float64, len(data.Snapshots)), base, true)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		si, sj := math.Abs(out[i].Score), math.Abs(out[j].Score)
+		if si != sj {
+			return si > sj
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,83 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// baselineCapture lists procs in four snapshots with iowait alternating
+// between wait and wait+1.
+func baselineCapture(wait float64, procs ...parser.ProcessData) parser.ReportData {
+	return capture(every(4, 2), func(i int, s *parser.Snapshot) {
+		s.Processes = procs
+		s.Metadata.CPUWait = wait + float64(i%2)
+	})
+}
+
+func TestBuildProfile(t *testing.T) {
+	p := BuildProfile([]parser.ReportData{
+		baselineCapture(1, parser.ProcessData{PID: 1, Command: "worker-1", CPU: 20}),
+		baselineCapture(3, parser.ProcessData{PID: 2, Command: "gc-1", CPU: 10}),
+	}, nil)
+	if p.Version != profileVersion || p.Captures != 2 || p.Snapshots != 8 {
+		t.Errorf("unexpected profile header: %+v", p)
+	}
+	if wait := p.Metrics[MetricIOWait]; wait.Samples != 8 || wait.Mean != 2.5 || wait.Min != 1 || wait.Max != 4 {
+		t.Errorf("unexpected iowait distribution: %+v", wait)
+	}
+	// each pool ran in only one of the two captures
+	for name, want := range map[string]float64{"worker": 10, "gc": 5} {
+		if d := p.Pools[name]; d.Samples != 8 || d.Mean != want {
+			t.Errorf("pool %s: expected 8 samples with mean %v, got %+v", name, want, d)
+		}
+	}
+}
+
+func TestProfileRoundTrip(t *testing.T) {
+	p := BuildProfile([]parser.ReportData{baselineCapture(1, parser.ProcessData{PID: 1, Command: "worker-1", CPU: 20})}, nil)
+	var buf bytes.Buffer
+	if err := WriteProfile(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadProfile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Pools["worker"] != p.Pools["worker"] || got.Metrics[MetricIOWait] != p.Metrics[MetricIOWait] {
+		t.Errorf("profile changed in a round trip: %+v", got)
+	}
+	if _, err := ReadProfile(strings.NewReader(`{"version": 99}`)); err == nil {
+		t.Error("expected an error for an unsupported version")
+	}
+}
+
+func TestScoreAgainst(t *testing.T) {
+	profile := BuildProfile([]parser.ReportData{
+		baselineCapture(1, parser.ProcessData{PID: 1, Command: "worker-1", CPU: 20}, parser.ProcessData{PID: 2, Command: "old-1", CPU: 5}),
+	}, nil)
+	current := baselineCapture(30, parser.ProcessData{PID: 1, Command: "worker-1", CPU: 20}, parser.ProcessData{PID: 3, Command: "new-1", CPU: 40})
+
+	devs := ScoreAgainst(current, profile, nil)
+	byName := make(map[string]Deviation)
+	for _, d := range devs {
+		byName[d.Name] = d
+	}
+	if d := byName[MetricIOWait]; d.Score < 10 || d.Outside != 1 || d.Kind != DeviationMetric {
+		t.Errorf("expected iowait far above the baseline, got %+v", d)
+	}
+	if d := byName["worker"]; d.Score != 0 || d.Outside != 0 {
+		t.Errorf("expected the worker pool to match the baseline, got %+v", d)
+	}
+	if d := byName["new"]; !d.New || d.Score != 40 {
+		t.Errorf("expected the new pool to be flagged, got %+v", d)
+	}
+	if d := byName["old"]; d.Mean != 0 || d.Score >= 0 {
+		t.Errorf("expected the missing pool to score below the baseline, got %+v", d)
+	}
+	if devs[0].Name != "new" {
+		t.Errorf("expected the largest deviation first, got %+v", devs[0])
+	}
+}
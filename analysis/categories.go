@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// CategoryApplication is the category of threads no rule matches.
+const CategoryApplication = "Application"
+
+// builtinCategoryRules classify common JVM, Netty and Dremio thread names.
+// Patterns are anchored at the start because top cuts names to 15 characters.
+var builtinCategoryRules = []CategoryRule{
+	{Pattern: `^(GC Thread|G1 |ZGC|ZDirector|ZStat|ZUnmapper|ZWorker|Shenandoah|Parallel GC|CMS )`, Category: "GC"},
+	{Pattern: `^(C[12] CompilerThre|Sweeper thread|JVMCI)`, Category: "JIT"},
+	{Pattern: `^(VM Thread|VM Periodic Tas|Signal Dispatch|Finalizer|Reference Handl|Service Thread|Common-Cleaner|Attach Listener|Notification Th)`, Category: "VM"},
+	{Pattern: `EventLoop|event-loop`, Category: "Netty"},
+	{Pattern: `^([0-9a-f]{8}-[0-9a-f-]*|e\d+ - )`, Category: "Dremio fragments"},
+	{Pattern: `^qtp\d+`, Category: "Jetty"},
+	{Pattern: `^ForkJoinPool`, Category: "ForkJoin"},
+}
+
+// CategoryRule maps thread names matching Pattern to Category.
+type CategoryRule struct {
+	Pattern  string `json:"pattern"`
+	Category string `json:"category"`
+}
+
+// CategoryConfig extends the built-in classification. User rules are tried
+// before the built-in ones, which can be switched off entirely.
+type CategoryConfig struct {
+	DisableBuiltin bool           `json:"disableBuiltin,omitempty"`
+	Rules          []CategoryRule `json:"rules,omitempty"`
+}
+
+// ReadCategoryConfig decodes a JSON category configuration.
+func ReadCategoryConfig(r io.Reader) (CategoryConfig, error) {
+	var cfg CategoryConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return CategoryConfig{}, fmt.Errorf("decode category config: %w", err)
+	}
+	return cfg, nil
+}
+
+type compiledCategoryRule struct {
+	re       *regexp.Regexp
+	category string
+}
+
+// Classifier maps thread names to categories such as GC or JIT.
+type Classifier struct {
+	rules []compiledCategoryRule
+}
+
+// DefaultClassifier returns a Classifier with only the built-in rules.
+func DefaultClassifier() *Classifier {
+	c, _ := NewClassifier(CategoryConfig{})
+	return c
+}
+
+// NewClassifier builds a Classifier from cfg, failing on invalid rule patterns.
+func NewClassifier(cfg CategoryConfig) (*Classifier, error) {
+	rules := cfg.Rules
+	if !cfg.DisableBuiltin {
+		rules = append(append([]CategoryRule(nil), rules...), builtinCategoryRules...)
+	}
+	c := &Classifier{}
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid category rule %q: %w", r.Pattern, err)
+		}
+		c.rules = append(c.rules, compiledCategoryRule{re: re, category: r.Category})
+	}
+	return c, nil
+}
+
+// Classify returns the category of the first rule matching name, or
+// CategoryApplication when none does.
+func (c *Classifier) Classify(name string) string {
+	for _, r := range c.rules {
+		if r.re.MatchString(name) {
+			return r.category
+		}
+	}
+	return CategoryApplication
+}
+
+// CategorySeries is the combined CPU of the threads in one category.
+type CategorySeries struct {
+	Name  string
+	CPU   []float64 // sum of member %CPU per snapshot
+	Share []float64 // CPU as a percentage of all listed threads' CPU per snapshot
+}
+
+// Categories classifies every thread and sums their CPU per category,
+// ordered by total CPU, busiest first.
+func Categories(data parser.ReportData, c *Classifier) []CategorySeries {
+	if c == nil {
+		c = DefaultClassifier()
+	}
+	n := len(data.Snapshots)
+	index := make(map[string]int)
+	var categories []CategorySeries
+	total := make([]float64, n)
+	for i, s := range data.Snapshots {
+		for _, p := range s.Processes {
+			name := c.Classify(p.Command)
+			idx, ok := index[name]
+			if !ok {
+				idx = len(categories)
+				index[name] = idx
+				categories = append(categories, CategorySeries{
+					Name:  name,
+					CPU:   make([]float64, n),
+					Share: make([]float64, n),
+				})
+			}
+			categories[idx].CPU[i] += p.CPU
+			total[i] += p.CPU
+		}
+	}
+	for _, cat := range categories {
+		for i := range cat.CPU {
+			if total[i] > 0 {
+				cat.Share[i] = cat.CPU[i] / total[i] * 100
+			}
+		}
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		return sum(categories[i].CPU) > sum(categories[j].CPU)
+	})
+	return categories
+}
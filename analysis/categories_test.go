@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestClassify_Builtin(t *testing.T) {
+	c := DefaultClassifier()
+	tests := map[string]string{
+		"GC Thread#3":     "GC",
+		"G1 Conc#0":       "GC",
+		"G1 Refine#0":     "GC",
+		"ZGC Worker":      "GC",
+		"C2 CompilerThre": "JIT",
+		"C1 CompilerThre": "JIT",
+		"VM Thread":       "VM",
+		"VM Periodic Tas": "VM",
+		"nioEventLoopGro": "Netty",
+		"epollEventLoopG": "Netty",
+		"1927b3c3-f":      "Dremio fragments",
+		"e3 - 1927b3c3-3": "Dremio fragments",
+		"qtp123456-42":    "Jetty",
+		"main":            CategoryApplication,
+		"rbound-command1": CategoryApplication,
+	}
+	for name, want := range tests {
+		if got := c.Classify(name); got != want {
+			t.Errorf("Classify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestClassify_UserRules(t *testing.T) {
+	cfg, err := ReadCategoryConfig(strings.NewReader(`{"rules": [{"pattern": "^rbound-", "category": "Rebound"}, {"pattern": "^G1 Refine", "category": "Refinement"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClassifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Classify("rbound-command1"); got != "Rebound" {
+		t.Errorf("expected the user rule to match, got %q", got)
+	}
+	if got := c.Classify("G1 Refine#0"); got != "Refinement" {
+		t.Errorf("expected user rules to win over built-ins, got %q", got)
+	}
+	if got := c.Classify("VM Thread"); got != "VM" {
+		t.Errorf("expected built-ins to still apply, got %q", got)
+	}
+
+	c, err = NewClassifier(CategoryConfig{DisableBuiltin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Classify("VM Thread"); got != CategoryApplication {
+		t.Errorf("expected built-ins to be disabled, got %q", got)
+	}
+}
+
+func TestReadCategoryConfig_Invalid(t *testing.T) {
+	if _, err := ReadCategoryConfig(strings.NewReader(`{"unknown": true}`)); err == nil {
+		t.Error("expected an error for unknown fields")
+	}
+	if _, err := NewClassifier(CategoryConfig{Rules: []CategoryRule{{Pattern: "("}}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestCategories(t *testing.T) {
+	data := capture(every(2, 2), listed(
+		[]parser.ProcessData{
+			{PID: 1, Command: "GC Thread#0", CPU: 30},
+			{PID: 2, Command: "GC Thread#1", CPU: 10},
+			{PID: 3, Command: "main", CPU: 60},
+		},
+		[]parser.ProcessData{{PID: 3, Command: "main", CPU: 50}},
+	))
+	cats := Categories(data, nil)
+	if len(cats) != 2 || cats[0].Name != CategoryApplication || cats[1].Name != "GC" {
+		t.Fatalf("unexpected categories: %+v", cats)
+	}
+	gc := cats[1]
+	if gc.CPU[0] != 40 || gc.Share[0] != 40 || gc.Share[1] != 0 {
+		t.Errorf("unexpected GC series: %+v", gc)
+	}
+	if cats[0].Share[1] != 100 {
+		t.Errorf("expected the application to hold all CPU in the second snapshot, got %v", cats[0].Share)
+	}
+}
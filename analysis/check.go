@@ -0,0 +1,359 @@
+package analysis
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// checkMetrics are the system metrics a check rule can name.
+var checkMetrics = map[string]func(parser.Metadata) float64{
+	"cpu":       func(m parser.Metadata) float64 { return m.CPUUser + m.CPUSystem },
+	"user":      func(m parser.Metadata) float64 { return m.CPUUser },
+	"system":    func(m parser.Metadata) float64 { return m.CPUSystem },
+	"idle":      func(m parser.Metadata) float64 { return m.CPUIdle },
+	"iowait":    func(m parser.Metadata) float64 { return m.CPUWait },
+	"steal":     func(m parser.Metadata) float64 { return m.CPUSteal },
+	"load1":     func(m parser.Metadata) float64 { return m.LoadAvg1 },
+	"load5":     func(m parser.Metadata) float64 { return m.LoadAvg5 },
+	"load15":    func(m parser.Metadata) float64 { return m.LoadAvg15 },
+	"mem_used":  func(m parser.Metadata) float64 { return m.MemUsed },
+	"mem_free":  func(m parser.Metadata) float64 { return m.MemFree },
+	"swap_used": func(m parser.Metadata) float64 { return m.SwapUsed },
+	"threads":   func(m parser.Metadata) float64 { return float64(m.ThreadsTotal) },
+	"running":   func(m parser.Metadata) float64 { return float64(m.ThreadsRunning) },
+	"sleeping":  func(m parser.Metadata) float64 { return float64(m.ThreadsSleeping) },
+	"stopped":   func(m parser.Metadata) float64 { return float64(m.ThreadsStopped) },
+	"zombie":    func(m parser.Metadata) float64 { return float64(m.ThreadsZombie) },
+}
+
+// checkStats reduce a series to the value compared by a rule.
+var checkStats = map[string]func(Summary) float64{
+	"min":    func(s Summary) float64 { return s.Min },
+	"mean":   func(s Summary) float64 { return s.Mean },
+	"median": func(s Summary) float64 { return s.Median },
+	"p95":    func(s Summary) float64 { return s.P95 },
+	"p99":    func(s Summary) float64 { return s.P99 },
+	"max":    func(s Summary) float64 { return s.Max },
+}
+
+var checkOps = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+// CheckRule is a declarative assertion about a capture. It either names a
+// system Metric or selects threads by a Threads glob, matched against the
+// thread name or its pool, in which case each thread's %CPU is checked.
+//
+// Without For the rule compares Stat (default max) of the series with Value.
+// With For the condition must hold in every snapshot, except that it may be
+// broken for at most that long at a time.
+type CheckRule struct {
+	Name    string  `json:"name"`
+	Metric  string  `json:"metric,omitempty"`
+	Threads string  `json:"threads,omitempty"`
+	Stat    string  `json:"stat,omitempty"`
+	Op      string  `json:"op"`
+	Value   float64 `json:"value"`
+	For     string  `json:"for,omitempty"`
+}
+
+// CheckConfig is a rules file for `ttoprep check`.
+type CheckConfig struct {
+	Rules []CheckRule `json:"rules"`
+}
+
+// ReadCheckConfig decodes and validates a JSON rules file.
+func ReadCheckConfig(r io.Reader) (CheckConfig, error) {
+	var cfg CheckConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return CheckConfig{}, fmt.Errorf("decode check rules: %w", err)
+	}
+	for i, rule := range cfg.Rules {
+		if _, err := compileCheckRule(rule); err != nil {
+			return CheckConfig{}, fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+	}
+	return cfg, nil
+}
+
+type compiledCheckRule struct {
+	CheckRule
+	metric  func(parser.Metadata) float64
+	threads *regexp.Regexp
+	stat    func(Summary) float64
+	op      func(a, b float64) bool
+	grace   time.Duration
+}
+
+func compileCheckRule(r CheckRule) (compiledCheckRule, error) {
+	c := compiledCheckRule{CheckRule: r}
+	switch {
+	case r.Threads != "":
+		if r.Metric != "" && r.Metric != "cpu" {
+			return c, fmt.Errorf("thread rules only check cpu, not %q", r.Metric)
+		}
+		c.threads = globRegexp(r.Threads)
+	case r.Metric != "":
+		if c.metric = checkMetrics[r.Metric]; c.metric == nil {
+			return c, fmt.Errorf("unknown metric %q", r.Metric)
+		}
+	default:
+		return c, fmt.Errorf("either metric or threads is required")
+	}
+	if c.op = checkOps[r.Op]; c.op == nil {
+		return c, fmt.Errorf("unknown op %q", r.Op)
+	}
+	stat := r.Stat
+	if stat == "" {
+		stat = "max"
+	}
+	if c.stat = checkStats[stat]; c.stat == nil {
+		return c, fmt.Errorf("unknown stat %q", r.Stat)
+	}
+	if r.For != "" {
+		if r.Stat != "" {
+			return c, fmt.Errorf("stat and for cannot be combined")
+		}
+		d, err := time.ParseDuration(r.For)
+		if err != nil {
+			return c, fmt.Errorf("invalid for: %w", err)
+		}
+		c.grace = d
+	}
+	return c, nil
+}
+
+// globRegexp turns a shell-style pattern with * and ? into an anchored regexp.
+func globRegexp(glob string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(glob)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// CheckViolation is one way a capture broke a rule.
+type CheckViolation struct {
+	Thread  string // "command (tid)" for thread rules
+	Start   int    // snapshot range of the violation; the whole capture for stats
+	End     int
+	Message string
+}
+
+// CheckResult is the outcome of one rule.
+type CheckResult struct {
+	Rule       CheckRule
+	Violations []CheckViolation
+}
+
+// Passed reports whether the rule held.
+func (r CheckResult) Passed() bool { return len(r.Violations) == 0 }
+
+// Describe renders the rule as a short expression such as "p95(steal) < 5".
+func (r CheckRule) Describe() string {
+	subject := r.Metric
+	if r.Threads != "" {
+		subject = fmt.Sprintf("cpu of threads %q", r.Threads)
+	}
+	if r.For != "" {
+		return fmt.Sprintf("%s %s %g (may break for up to %s)", subject, r.Op, r.Value, r.For)
+	}
+	stat := r.Stat
+	if stat == "" {
+		stat = "max"
+	}
+	return fmt.Sprintf("%s(%s) %s %g", stat, subject, r.Op, r.Value)
+}
+
+// EvaluateChecks runs every rule against data. n maps thread names to pools
+// for thread rules; nil uses the built-in normalization. A capture without
+// snapshots is an error and a thread rule matching no thread fails, so a
+// truncated capture or a stale selector cannot pass a CI gate.
+func EvaluateChecks(data parser.ReportData, cfg CheckConfig, n *Normalizer) ([]CheckResult, error) {
+	if len(data.Snapshots) == 0 {
+		return nil, fmt.Errorf("capture has no snapshots")
+	}
+	if n == nil {
+		n = DefaultNormalizer()
+	}
+	offsets := data.Offsets()
+	intervals := Intervals(data)
+	var results []CheckResult
+	for i, rule := range cfg.Rules {
+		c, err := compileCheckRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+		result := CheckResult{Rule: rule}
+
+		type series struct {
+			thread  string
+			values  []float64
+			present []bool
+		}
+		var inputs []series
+		if c.threads != nil {
+			for _, t := range Threads(data) {
+				if c.threads.MatchString(t.Command) || c.threads.MatchString(n.Normalize(t.Command)) {
+					inputs = append(inputs, series{fmt.Sprintf("%s (%d)", t.Command, t.TID), t.CPU, t.Present})
+				}
+			}
+			if len(inputs) == 0 {
+				result.Violations = append(result.Violations, CheckViolation{
+					End:     len(data.Snapshots) - 1,
+					Message: fmt.Sprintf("threads %q matched no thread", rule.Threads),
+				})
+			}
+		} else {
+			values := make([]float64, len(data.Snapshots))
+			present := make([]bool, len(data.Snapshots))
+			for j, s := range data.Snapshots {
+				values[j] = c.metric(s.Metadata)
+				present[j] = true
+			}
+			inputs = append(inputs, series{values: values, present: present})
+		}
+
+		for _, in := range inputs {
+			if c.For == "" {
+				var seen []float64
+				for j, v := range in.values {
+					if in.present[j] {
+						seen = append(seen, v)
+					}
+				}
+				if len(seen) == 0 {
+					continue
+				}
+				actual := c.stat(Summarize(seen))
+				if !c.op(actual, c.Value) {
+					result.Violations = append(result.Violations, CheckViolation{
+						Thread:  in.thread,
+						Start:   0,
+						End:     len(data.Snapshots) - 1,
+						Message: fmt.Sprintf("%s is %.2f", strings.TrimSpace(in.thread+" "+rule.Describe()), actual),
+					})
+				}
+				continue
+			}
+
+			// sustained breaks of the condition longer than the grace period
+			start := -1
+			for j := 0; j <= len(in.values); j++ {
+				broken := j < len(in.values) && in.present[j] && !c.op(in.values[j], c.Value)
+				if broken {
+					if start == -1 {
+						start = j
+					}
+					continue
+				}
+				if start == -1 {
+					continue
+				}
+				if d := offsets[j-1] - offsets[start] + intervals[j-1]; d > c.grace {
+					result.Violations = append(result.Violations, CheckViolation{
+						Thread: in.thread,
+						Start:  start,
+						End:    j - 1,
+						Message: fmt.Sprintf("%s broke %s %g for %s (peak %.2f)",
+							strings.TrimSpace(in.thread+" "+orMetric(rule)), rule.Op, rule.Value, d, maxOf(in.values[start:j])),
+					})
+				}
+				start = -1
+			}
+		}
+		sort.SliceStable(result.Violations, func(a, b int) bool {
+			return result.Violations[a].Start < result.Violations[b].Start
+		})
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func orMetric(r CheckRule) string {
+	if r.Threads != "" {
+		return "cpu"
+	}
+	return r.Metric
+}
+
+func maxOf(values []float64) float64 {
+	m := 0.0
+	for i, v := range values {
+		if i == 0 || v > m {
+			m = v
+		}
+	}
+	return m
+}
+
+// junit* mirror the subset of the JUnit XML format CI dashboards read.
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as a JUnit XML test suite, one test case per
+// rule.
+func WriteJUnit(w io.Writer, suite string, results []CheckResult) error {
+	s := junitSuite{Name: suite, Tests: len(results)}
+	for _, r := range results {
+		name := r.Rule.Name
+		if name == "" {
+			name = r.Rule.Describe()
+		}
+		c := junitCase{Name: name, Classname: "ttoprep.check"}
+		if !r.Passed() {
+			s.Failures++
+			var lines []string
+			for _, v := range r.Violations {
+				lines = append(lines, v.Message)
+			}
+			c.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s: %d violation(s)", r.Rule.Describe(), len(r.Violations)),
+				Text:    strings.Join(lines, "\n"),
+			}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write junit: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("encode junit: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("write junit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package analysis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// checkCapture has a snapshot every 10s: steal climbs to 9% at the end, one
+// C2 compiler thread is above 80% for 40s and another for only 20s.
+func checkCapture() parser.ReportData {
+	hot := []float64{10, 90, 95, 92, 85, 10}
+	brief := []float64{10, 10, 90, 90, 10, 10}
+	return capture(every(len(hot), 10), func(i int, s *parser.Snapshot) {
+		s.Processes = []parser.ProcessData{
+			{PID: 1, Command: "C2 CompilerThre", CPU: hot[i]},
+			{PID: 2, Command: "C2 CompilerThre", CPU: brief[i]},
+			{PID: 3, Command: "worker-1", CPU: 99},
+		}
+		s.Metadata.CPUSteal = 1
+		if i == len(hot)-1 {
+			s.Metadata.CPUSteal = 9
+		}
+	})
+}
+
+func TestReadCheckConfig(t *testing.T) {
+	cfg, err := ReadCheckConfig(strings.NewReader(`{"rules": [
+		{"name": "steal", "metric": "steal", "stat": "p95", "op": "<", "value": 5},
+		{"threads": "C2 Compiler*", "op": "<=", "value": 80, "for": "30s"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Rules) != 2 || cfg.Rules[1].For != "30s" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	for _, bad := range []string{
+		`{"rules": [{"metric": "nope", "op": "<", "value": 1}]}`,
+		`{"rules": [{"metric": "steal", "op": "~", "value": 1}]}`,
+		`{"rules": [{"metric": "steal", "stat": "p42", "op": "<", "value": 1}]}`,
+		`{"rules": [{"op": "<", "value": 1}]}`,
+		`{"rules": [{"metric": "steal", "op": "<", "value": 1, "for": "soon"}]}`,
+		`{"rules": [{"metric": "steal", "stat": "max", "op": "<", "value": 1, "for": "30s"}]}`,
+		`{"rules": [{"threads": "C2*", "metric": "steal", "op": "<", "value": 1}]}`,
+		`{"rules": [{"metric": "steal", "op": "<", "value": 1, "typo": true}]}`,
+	} {
+		if _, err := ReadCheckConfig(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestEvaluateChecks(t *testing.T) {
+	cfg := CheckConfig{Rules: []CheckRule{
+		{Name: "steal", Metric: "steal", Stat: "p95", Op: "<", Value: 5},
+		{Name: "compiler", Threads: "C2 Compiler*", Op: "<=", Value: 80, For: "30s"},
+		{Name: "zombies", Metric: "zombie", Op: "==", Value: 0},
+		{Name: "workers", Threads: "worker", Stat: "mean", Op: "<", Value: 100},
+	}}
+	results, err := EvaluateChecks(checkCapture(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected a result per rule, got %d", len(results))
+	}
+
+	if results[0].Passed() || !strings.Contains(results[0].Violations[0].Message, "p95(steal) < 5") {
+		t.Errorf("expected p95 steal to fail, got %+v", results[0])
+	}
+	compiler := results[1]
+	if len(compiler.Violations) != 1 {
+		t.Fatalf("expected only the sustained compiler thread to fail, got %+v", compiler.Violations)
+	}
+	if v := compiler.Violations[0]; v.Thread != "C2 CompilerThre (1)" || v.Start != 1 || v.End != 4 || !strings.Contains(v.Message, "for 40s (peak 95.00)") {
+		t.Errorf("unexpected compiler violation: %+v", v)
+	}
+	// the worker glob matches the pool name rather than the thread name
+	for _, r := range results[2:] {
+		if !r.Passed() {
+			t.Errorf("expected %s to pass, got %+v", r.Rule.Name, r.Violations)
+		}
+	}
+}
+
+func TestEvaluateChecks_NoSnapshots(t *testing.T) {
+	cfg := CheckConfig{Rules: []CheckRule{{Metric: "steal", Op: "<", Value: 5}}}
+	if _, err := EvaluateChecks(parser.ReportData{}, cfg, nil); err == nil {
+		t.Error("expected an error for a capture without snapshots")
+	}
+}
+
+func TestEvaluateChecks_UnmatchedThreads(t *testing.T) {
+	cfg := CheckConfig{Rules: []CheckRule{
+		{Threads: "nope*", Op: "<", Value: 80},
+		{Threads: "nope*", Op: "<", Value: 80, For: "30s"},
+	}}
+	results, err := EvaluateChecks(checkCapture(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Passed() || !strings.Contains(r.Violations[0].Message, `threads "nope*" matched no thread`) {
+			t.Errorf("expected a rule matching no thread to fail, got %+v", r)
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "checks", results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `failures="2"`) {
+		t.Errorf("expected both rules as JUnit failures, got %s", buf.String())
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	results := []CheckResult{
+		{Rule: CheckRule{Name: "steal", Metric: "steal", Stat: "p95", Op: "<", Value: 5},
+			Violations: []CheckViolation{{Message: "p95(steal) < 5 is 9.00"}}},
+		{Rule: CheckRule{Metric: "zombie", Op: "==", Value: 0}},
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "capture.txt", results); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<testsuite name="capture.txt" tests="2" failures="1">`,
+		`<testcase name="steal" classname="ttoprep.check">`,
+		`<failure message="p95(steal) &lt; 5: 1 violation(s)">p95(steal) &lt; 5 is 9.00</failure>`,
+		`<testcase name="max(zombie) == 0" classname="ttoprep.check"></testcase>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+}
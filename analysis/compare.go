@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// SystemMetric is a per-snapshot system metric compared between captures.
+type SystemMetric struct {
+	Name string
+	Unit string
+	Get  func(parser.Metadata) float64
+}
+
+// SystemMetrics lists the metrics Compare looks at. For all of them a higher
+// value is worse.
+var SystemMetrics = []SystemMetric{
+	{MetricTotalCPU, "%", func(m parser.Metadata) float64 { return m.CPUUser + m.CPUSystem }},
+	{MetricIOWait, "%", func(m parser.Metadata) float64 { return m.CPUWait }},
+	{MetricSteal, "%", func(m parser.Metadata) float64 { return m.CPUSteal }},
+	{MetricLoad1, "", func(m parser.Metadata) float64 { return m.LoadAvg1 }},
+	{MetricMemUsed, "MiB", func(m parser.Metadata) float64 { return m.MemUsed }},
+	{MetricSwapUsed, "MiB", func(m parser.Metadata) float64 { return m.SwapUsed }},
+	{"Threads", "", func(m parser.Metadata) float64 { return float64(m.ThreadsTotal) }},
+}
+
+// Values returns the metric for every snapshot of data.
+func (m SystemMetric) Values(data parser.ReportData) []float64 {
+	values := make([]float64, len(data.Snapshots))
+	for i, s := range data.Snapshots {
+		values[i] = m.Get(s.Metadata)
+	}
+	return values
+}
+
+// MetricComparison compares the distribution of one system metric.
+type MetricComparison struct {
+	Name          string
+	Unit          string
+	Before, After Summary
+}
+
+// Delta is the change in the mean.
+func (c MetricComparison) Delta() float64 { return c.After.Mean - c.Before.Mean }
+
+// CPUComparison compares the mean %CPU of a thread name or pool. Threads are
+// matched by name because TIDs differ between runs; threads sharing a name
+// are summed.
+type CPUComparison struct {
+	Name          string
+	Before, After float64 // mean %CPU over the capture
+}
+
+// Delta is the change in mean %CPU; positive values are regressions.
+func (c CPUComparison) Delta() float64 { return c.After - c.Before }
+
+// Comparison is the result of Compare.
+type Comparison struct {
+	Metrics []MetricComparison
+	// Threads and Pools are ordered by the size of the change, largest first
+	Threads []CPUComparison
+	Pools   []CPUComparison
+}
+
+// Compare compares the system metric distributions and per-thread and
+// per-pool CPU of two captures.
+func Compare(before, after parser.ReportData, n *Normalizer) Comparison {
+	if n == nil {
+		n = DefaultNormalizer()
+	}
+	var cmp Comparison
+	for _, m := range SystemMetrics {
+		cmp.Metrics = append(cmp.Metrics, MetricComparison{
+			Name:   m.Name,
+			Unit:   m.Unit,
+			Before: Summarize(m.Values(before)),
+			After:  Summarize(m.Values(after)),
+		})
+	}
+	byName := func(name string) string { return name }
+	cmp.Threads = compareCPU(meanCPUBy(before, byName), meanCPUBy(after, byName))
+	cmp.Pools = compareCPU(meanCPUBy(before, n.Normalize), meanCPUBy(after, n.Normalize))
+	return cmp
+}
+
+// meanCPUBy sums thread CPU per key and averages it over the capture.
+func meanCPUBy(data parser.ReportData, key func(string) string) map[string]float64 {
+	means := make(map[string]float64)
+	if len(data.Snapshots) == 0 {
+		return means
+	}
+	for _, s := range data.Snapshots {
+		for _, p := range s.Processes {
+			means[key(p.Command)] += p.CPU
+		}
+	}
+	for k := range means {
+		means[k] /= float64(len(data.Snapshots))
+	}
+	return means
+}
+
+func compareCPU(before, after map[string]float64) []CPUComparison {
+	var out []CPUComparison
+	for name, b := range before {
+		out = append(out, CPUComparison{Name: name, Before: b, After: after[name]})
+	}
+	for name, a := range after {
+		if _, ok := before[name]; !ok {
+			out = append(out, CPUComparison{Name: name, After: a})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		di, dj := math.Abs(out[i].Delta()), math.Abs(out[j].Delta())
+		if di != dj {
+			return di > dj
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,46 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestCompare(t *testing.T) {
+	// two snapshots 2s apart with the given user CPU and threads each
+	build := func(user [2]float64, rows ...[]parser.ProcessData) parser.ReportData {
+		return capture(every(2, 2), func(i int, s *parser.Snapshot) {
+			s.Processes = rows[i]
+			s.Metadata.CPUUser = user[i]
+		})
+	}
+	before := build([2]float64{20, 40},
+		[]parser.ProcessData{{PID: 1, Command: "worker-1", CPU: 10}, {PID: 2, Command: "gc", CPU: 40}},
+		[]parser.ProcessData{{PID: 1, Command: "worker-1", CPU: 30}, {PID: 2, Command: "gc", CPU: 40}},
+	)
+	after := build([2]float64{60, 80},
+		[]parser.ProcessData{{PID: 7, Command: "worker-1", CPU: 50}, {PID: 8, Command: "worker-2", CPU: 50}},
+		[]parser.ProcessData{{PID: 7, Command: "worker-1", CPU: 70}, {PID: 9, Command: "gc", CPU: 10}},
+	)
+	cmp := Compare(before, after, nil)
+
+	if cmp.Metrics[0].Name != MetricTotalCPU || cmp.Metrics[0].Delta() != 40 {
+		t.Errorf("expected total CPU to rise by 40, got %+v", cmp.Metrics[0])
+	}
+
+	// worker-1: 20 -> 60, gc: 40 -> 5, worker-2: 0 -> 25
+	want := []CPUComparison{{"worker-1", 20, 60}, {"gc", 40, 5}, {"worker-2", 0, 25}}
+	if len(cmp.Threads) != len(want) {
+		t.Fatalf("expected %d threads, got %+v", len(want), cmp.Threads)
+	}
+	for i := range want {
+		if cmp.Threads[i] != want[i] {
+			t.Errorf("thread %d: expected %+v, got %+v", i, want[i], cmp.Threads[i])
+		}
+	}
+
+	// worker-1 and worker-2 share the "worker" pool: 20 -> 85
+	if len(cmp.Pools) != 2 || cmp.Pools[0] != (CPUComparison{"worker", 20, 85}) {
+		t.Errorf("unexpected pools: %+v", cmp.Pools)
+	}
+}
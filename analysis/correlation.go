@@ -0,0 +1,133 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// Correlation subject kinds
+const (
+	CorrelationThread = "thread"
+	CorrelationPool   = "pool"
+)
+
+// CorrelationMetrics are the system series thread and pool CPU is correlated
+// with.
+var CorrelationMetrics = []SystemMetric{
+	{MetricIOWait, "%", func(m parser.Metadata) float64 { return m.CPUWait }},
+	{MetricSteal, "%", func(m parser.Metadata) float64 { return m.CPUSteal }},
+	{"System CPU", "%", func(m parser.Metadata) float64 { return m.CPUSystem }},
+	{MetricLoad1, "", func(m parser.Metadata) float64 { return m.LoadAvg1 }},
+	{"Running threads", "", func(m parser.Metadata) float64 { return float64(m.ThreadsRunning) }},
+}
+
+// minCorrelationPeak skips threads and pools that never reach this %CPU;
+// the noise of idle threads otherwise produces meaningless coefficients.
+const minCorrelationPeak = 1.0
+
+// minCorrelationSamples is the fewest snapshots worth correlating.
+const minCorrelationSamples = 5
+
+// Correlation is how closely a thread's or pool's CPU moves with a system
+// metric. Both coefficients are in [-1, 1].
+type Correlation struct {
+	Kind     string
+	Subject  string
+	Metric   string
+	Pearson  float64
+	Spearman float64
+}
+
+// Strength is the larger magnitude of the two coefficients.
+func (c Correlation) Strength() float64 {
+	return math.Max(math.Abs(c.Pearson), math.Abs(c.Spearman))
+}
+
+// Pearson returns the linear correlation coefficient of x and y, or 0 when
+// either is constant.
+func Pearson(x, y []float64) float64 {
+	n := len(x)
+	if n != len(y) || n < 2 {
+		return 0
+	}
+	mx, my := Mean(x), Mean(y)
+	var sxy, sxx, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// Spearman returns the rank correlation coefficient of x and y, which also
+// catches monotonic but non-linear relationships. Ties get their average rank.
+func Spearman(x, y []float64) float64 {
+	return Pearson(ranks(x), ranks(y))
+}
+
+func ranks(values []float64) []float64 {
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return values[idx[a]] < values[idx[b]] })
+	out := make([]float64, len(values))
+	for i := 0; i < len(idx); {
+		j := i
+		for j+1 < len(idx) && values[idx[j+1]] == values[idx[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			out[idx[k]] = rank
+		}
+		i = j + 1
+	}
+	return out
+}
+
+// Correlations correlates the CPU of every busy thread and pool with each of
+// CorrelationMetrics, strongest first. Threads count as 0% CPU in snapshots
+// where they were not listed.
+func Correlations(data parser.ReportData, n *Normalizer) []Correlation {
+	if len(data.Snapshots) < minCorrelationSamples {
+		return nil
+	}
+	metrics := make([][]float64, len(CorrelationMetrics))
+	for i, m := range CorrelationMetrics {
+		metrics[i] = m.Values(data)
+	}
+	var out []Correlation
+	add := func(kind, subject string, cpu []float64) {
+		if maxOf(cpu) < minCorrelationPeak {
+			return
+		}
+		for i, m := range CorrelationMetrics {
+			c := Correlation{Kind: kind, Subject: subject, Metric: m.Name, Pearson: Pearson(cpu, metrics[i]), Spearman: Spearman(cpu, metrics[i])}
+			if c.Pearson != 0 || c.Spearman != 0 {
+				out = append(out, c)
+			}
+		}
+	}
+	for _, t := range Threads(data) {
+		add(CorrelationThread, fmt.Sprintf("%s (%d)", t.Command, t.TID), t.CPU)
+	}
+	for _, p := range Pools(data, n) {
+		// a pool of one thread repeats that thread's row
+		if len(p.TIDs) > 1 {
+			add(CorrelationPool, p.Name, p.CPU)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Strength() > out[j].Strength()
+	})
+	return out
+}
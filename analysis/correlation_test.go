@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestPearsonAndSpearman(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	for _, tc := range []struct {
+		name              string
+		y                 []float64
+		pearson, spearman float64
+	}{
+		{"linear", []float64{2, 4, 6, 8, 10}, 1, 1},
+		{"inverse", []float64{5, 4, 3, 2, 1}, -1, -1},
+		{"monotonic", []float64{1, 2, 4, 8, 100}, 0.75, 1},
+		{"constant", []float64{3, 3, 3, 3, 3}, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if p := Pearson(x, tc.y); math.Abs(p-tc.pearson) > 0.01 {
+				t.Errorf("expected pearson %v, got %v", tc.pearson, p)
+			}
+			if s := Spearman(x, tc.y); math.Abs(s-tc.spearman) > 0.01 {
+				t.Errorf("expected spearman %v, got %v", tc.spearman, s)
+			}
+		})
+	}
+}
+
+func TestRanks_AveragesTies(t *testing.T) {
+	if got, want := ranks([]float64{10, 20, 10, 30}), []float64{1.5, 3, 1.5, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCorrelations(t *testing.T) {
+	wait := []float64{1, 20, 2, 25, 1, 30}
+	data := capture(every(len(wait), 2), func(i int, s *parser.Snapshot) {
+		w := wait[i]
+		s.Processes = []parser.ProcessData{
+			{PID: 1, Command: "writer-1", CPU: w / 2},
+			{PID: 2, Command: "writer-2", CPU: w},
+			{PID: 3, Command: "steady", CPU: float64(10 + i%2)},
+			{PID: 4, Command: "idle", CPU: 0.1 * float64(i)},
+		}
+		s.Metadata.CPUWait = w
+	})
+	corr := Correlations(data, nil)
+	if len(corr) == 0 {
+		t.Fatal("expected correlations")
+	}
+	top := corr[0]
+	if top.Metric != MetricIOWait || top.Pearson < 0.99 || top.Spearman < 0.99 {
+		t.Errorf("expected iowait to correlate perfectly first, got %+v", top)
+	}
+	kinds := make(map[string]bool)
+	for _, c := range corr {
+		if c.Subject == "idle (4)" {
+			t.Errorf("expected the idle thread to be skipped, got %+v", c)
+		}
+		if c.Metric == MetricIOWait {
+			kinds[c.Kind+" "+c.Subject] = true
+		}
+	}
+	for _, want := range []string{"thread writer-1 (1)", "thread writer-2 (2)", "pool writer", "thread steady (3)"} {
+		if !kinds[want] {
+			t.Errorf("expected an iowait correlation for %s, got %v", want, kinds)
+		}
+	}
+	if got := Correlations(parser.ReportData{Snapshots: data.Snapshots[:3]}, nil); got != nil {
+		t.Errorf("expected too short a capture to be skipped, got %+v", got)
+	}
+}
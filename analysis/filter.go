@@ -0,0 +1,167 @@
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// FilterConfig selects the threads kept in a report. Empty fields keep
+// everything. States and MinCPU judge a thread over the whole capture, so a
+// kept thread keeps all its snapshots and its series has no holes.
+type FilterConfig struct {
+	Users          []string // keep threads owned by one of these users
+	TIDs           []int    // keep only these threads
+	Command        string   // regexp the thread name must match
+	ExcludeCommand string   // regexp the thread name must not match
+	States         []string // keep threads listed in one of these states at least once
+	MinCPU         float64  // keep threads whose peak %CPU reaches this
+}
+
+// ThreadFilter is a compiled FilterConfig.
+type ThreadFilter struct {
+	cfg     FilterConfig
+	users   map[string]bool
+	tids    map[int]bool
+	states  map[string]bool
+	command *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+// NewThreadFilter compiles cfg, failing on an invalid regexp.
+func NewThreadFilter(cfg FilterConfig) (*ThreadFilter, error) {
+	f := &ThreadFilter{cfg: cfg}
+	var err error
+	if cfg.Command != "" {
+		if f.command, err = regexp.Compile(cfg.Command); err != nil {
+			return nil, fmt.Errorf("invalid command regex: %w", err)
+		}
+	}
+	if cfg.ExcludeCommand != "" {
+		if f.exclude, err = regexp.Compile(cfg.ExcludeCommand); err != nil {
+			return nil, fmt.Errorf("invalid exclude command regex: %w", err)
+		}
+	}
+	if len(cfg.Users) > 0 {
+		f.users = make(map[string]bool)
+		for _, u := range cfg.Users {
+			f.users[u] = true
+		}
+	}
+	if len(cfg.TIDs) > 0 {
+		f.tids = make(map[int]bool)
+		for _, tid := range cfg.TIDs {
+			f.tids[tid] = true
+		}
+	}
+	if len(cfg.States) > 0 {
+		f.states = make(map[string]bool)
+		for _, s := range cfg.States {
+			f.states[strings.ToUpper(s)] = true
+		}
+	}
+	return f, nil
+}
+
+// IsZero reports whether the filter keeps every thread.
+func (f *ThreadFilter) IsZero() bool {
+	return f == nil || len(f.Describe()) == 0
+}
+
+// Describe lists the active conditions, e.g. `user = dremio` or
+// `command !~ /^GC/`, for recording in the report.
+func (f *ThreadFilter) Describe() []string {
+	if f == nil {
+		return nil
+	}
+	var out []string
+	if len(f.cfg.Users) > 0 {
+		out = append(out, "user = "+strings.Join(f.cfg.Users, ", "))
+	}
+	if len(f.cfg.TIDs) > 0 {
+		tids := make([]string, len(f.cfg.TIDs))
+		for i, tid := range f.cfg.TIDs {
+			tids[i] = strconv.Itoa(tid)
+		}
+		out = append(out, "tid = "+strings.Join(tids, ", "))
+	}
+	if f.command != nil {
+		out = append(out, "command =~ /"+f.cfg.Command+"/")
+	}
+	if f.exclude != nil {
+		out = append(out, "command !~ /"+f.cfg.ExcludeCommand+"/")
+	}
+	if len(f.cfg.States) > 0 {
+		out = append(out, "state = "+strings.ToUpper(strings.Join(f.cfg.States, ", ")))
+	}
+	if f.cfg.MinCPU > 0 {
+		out = append(out, fmt.Sprintf("peak %%CPU ≥ %g", f.cfg.MinCPU))
+	}
+	return out
+}
+
+// keepRow applies the conditions that are decided per listed row.
+func (f *ThreadFilter) keepRow(p parser.ProcessData) bool {
+	if f.users != nil && !f.users[p.User] {
+		return false
+	}
+	if f.tids != nil && !f.tids[p.PID] {
+		return false
+	}
+	if f.command != nil && !f.command.MatchString(p.Command) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(p.Command) {
+		return false
+	}
+	return true
+}
+
+// Apply returns a copy of data keeping only the matching threads, and how
+// many distinct threads it hid. System metrics are left untouched.
+func (f *ThreadFilter) Apply(data parser.ReportData) (parser.ReportData, int) {
+	if f.IsZero() {
+		return data, 0
+	}
+	// states and peak CPU are judged over the whole capture
+	seenState := make(map[int]bool)
+	peak := make(map[int]float64)
+	all := make(map[int]bool)
+	for _, s := range data.Snapshots {
+		for _, p := range s.Processes {
+			all[p.PID] = true
+			if f.states[strings.ToUpper(p.S)] {
+				seenState[p.PID] = true
+			}
+			if p.CPU > peak[p.PID] {
+				peak[p.PID] = p.CPU
+			}
+		}
+	}
+	keep := func(p parser.ProcessData) bool {
+		if !f.keepRow(p) {
+			return false
+		}
+		if f.states != nil && !seenState[p.PID] {
+			return false
+		}
+		return peak[p.PID] >= f.cfg.MinCPU
+	}
+
+	out := parser.ReportData{Snapshots: make([]parser.Snapshot, len(data.Snapshots))}
+	kept := make(map[int]bool)
+	for i, s := range data.Snapshots {
+		s.Processes = nil
+		for _, p := range data.Snapshots[i].Processes {
+			if keep(p) {
+				s.Processes = append(s.Processes, p)
+				kept[p.PID] = true
+			}
+		}
+		out.Snapshots[i] = s
+	}
+	return out, len(all) - len(kept)
+}
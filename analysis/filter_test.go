@@ -0,0 +1,99 @@
+package analysis
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func filterCapture() parser.ReportData {
+	return capture(every(2, 2), listed(
+		[]parser.ProcessData{
+			{PID: 1, User: "dremio", Command: "worker-1", CPU: 50, S: "R"},
+			{PID: 2, User: "dremio", Command: "GC Thread#0", CPU: 1, S: "S"},
+			{PID: 3, User: "root", Command: "sshd", CPU: 0, S: "S"},
+		},
+		[]parser.ProcessData{
+			{PID: 1, User: "dremio", Command: "worker-1", CPU: 2, S: "S"},
+			{PID: 2, User: "dremio", Command: "GC Thread#0", CPU: 3, S: "D"},
+			{PID: 4, User: "dremio", Command: "worker-2", CPU: 0, S: "S"},
+		},
+	))
+}
+
+func keptTIDs(data parser.ReportData) []int {
+	seen := make(map[int]bool)
+	var tids []int
+	for _, s := range data.Snapshots {
+		for _, p := range s.Processes {
+			if !seen[p.PID] {
+				seen[p.PID] = true
+				tids = append(tids, p.PID)
+			}
+		}
+	}
+	sort.Ints(tids)
+	return tids
+}
+
+func TestThreadFilter(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		cfg    FilterConfig
+		kept   []int
+		hidden int
+	}{
+		{"none", FilterConfig{}, []int{1, 2, 3, 4}, 0},
+		{"user", FilterConfig{Users: []string{"root"}}, []int{3}, 3},
+		{"tid", FilterConfig{TIDs: []int{1, 4}}, []int{1, 4}, 2},
+		{"command", FilterConfig{Command: "^worker"}, []int{1, 4}, 2},
+		{"exclude command", FilterConfig{ExcludeCommand: "^GC"}, []int{1, 3, 4}, 1},
+		{"state keeps the whole thread", FilterConfig{States: []string{"d"}}, []int{2}, 3},
+		{"peak cpu", FilterConfig{MinCPU: 3}, []int{1, 2}, 2},
+		{"combined", FilterConfig{Users: []string{"dremio"}, ExcludeCommand: "GC", MinCPU: 1}, []int{1}, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewThreadFilter(tc.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := filterCapture()
+			got, hidden := f.Apply(data)
+			if tids := keptTIDs(got); !reflect.DeepEqual(tids, tc.kept) || hidden != tc.hidden {
+				t.Errorf("expected %v kept and %d hidden, got %v and %d", tc.kept, tc.hidden, tids, hidden)
+			}
+			if len(got.Snapshots) != 2 {
+				t.Errorf("expected every snapshot to be kept, got %d", len(got.Snapshots))
+			}
+			if len(data.Snapshots[0].Processes) != 3 {
+				t.Error("the input capture was modified")
+			}
+		})
+	}
+}
+
+func TestThreadFilter_Describe(t *testing.T) {
+	f, err := NewThreadFilter(FilterConfig{
+		Users:          []string{"dremio"},
+		TIDs:           []int{1, 2},
+		Command:        "worker",
+		ExcludeCommand: "GC",
+		States:         []string{"r", "D"},
+		MinCPU:         5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"user = dremio", "tid = 1, 2", "command =~ /worker/", "command !~ /GC/", "state = R, D", "peak %CPU ≥ 5"}
+	if got := f.Describe(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if empty, _ := NewThreadFilter(FilterConfig{}); !empty.IsZero() {
+		t.Error("expected an empty config to keep every thread")
+	}
+	if _, err := NewThreadFilter(FilterConfig{Command: "("}); err == nil {
+		t.Error("expected an error for an invalid regexp")
+	}
+}
@@ -0,0 +1,410 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// Severity ranks findings; higher is worse.
+type Severity int
+
+// Finding severities
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityCritical:
+		return "critical"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Finding is a problem a Rule spotted in a capture, with the evidence behind
+// it.
+type Finding struct {
+	Rule        string // name of the rule that raised it
+	Severity    Severity
+	Title       string
+	Explanation string
+	Start       int      // first snapshot of the evidence
+	End         int      // last snapshot of the evidence
+	Threads     []string // involved threads as "command (tid)"
+}
+
+// RuleInput is what rules inspect.
+type RuleInput struct {
+	Data  parser.ReportData
+	Cores int // host core count; 0 when unknown
+}
+
+// Rule inspects a capture and explains what it finds. Rules return nil when
+// the capture looks healthy to them.
+type Rule interface {
+	Name() string
+	Evaluate(in RuleInput) []Finding
+}
+
+// BuiltinRules returns the rules shipped with ttoprep. sat tunes the hot
+// thread rule the same way as the saturation analysis.
+func BuiltinRules(sat SaturationConfig) []Rule {
+	return []Rule{
+		IOWaitRule{},
+		StealRule{},
+		SwapRule{},
+		LoadRule{},
+		ZombieRule{},
+		HotThreadRule{Config: sat},
+	}
+}
+
+// RunRules evaluates every rule and orders the findings by severity, worst
+// first, then by when they started.
+func RunRules(in RuleInput, rules []Rule) []Finding {
+	var findings []Finding
+	for _, r := range rules {
+		for _, f := range r.Evaluate(in) {
+			if f.Rule == "" {
+				f.Rule = r.Name()
+			}
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		return findings[i].Start < findings[j].Start
+	})
+	return findings
+}
+
+// maxFindingThreads caps the threads listed as evidence of a finding.
+const maxFindingThreads = 5
+
+// topThreads ranks the threads listed in snapshots start..end by the sum of
+// score over them and returns up to maxFindingThreads with a positive total.
+func topThreads(data parser.ReportData, start, end int, score func(parser.ProcessData) float64) []string {
+	totals := make(map[int]float64)
+	names := make(map[int]string)
+	for i := start; i <= end; i++ {
+		for _, p := range data.Snapshots[i].Processes {
+			if v := score(p); v > 0 {
+				totals[p.PID] += v
+				names[p.PID] = p.Command
+			}
+		}
+	}
+	tids := make([]int, 0, len(totals))
+	for tid := range totals {
+		tids = append(tids, tid)
+	}
+	sort.Slice(tids, func(i, j int) bool {
+		if totals[tids[i]] != totals[tids[j]] {
+			return totals[tids[i]] > totals[tids[j]]
+		}
+		return tids[i] < tids[j]
+	})
+	if len(tids) > maxFindingThreads {
+		tids = tids[:maxFindingThreads]
+	}
+	var out []string
+	for _, tid := range tids {
+		out = append(out, fmt.Sprintf("%s (%d)", names[tid], tid))
+	}
+	return out
+}
+
+// metricPeriod is a sustained stretch of a system metric above a threshold.
+type metricPeriod struct {
+	Start, End int
+	Duration   time.Duration
+	Mean, Peak float64
+}
+
+// sustainedAbove finds the periods where get stays at or above threshold for
+// at least minDuration.
+func sustainedAbove(data parser.ReportData, threshold float64, minDuration time.Duration, get func(parser.Metadata) float64) []metricPeriod {
+	values := make([]float64, len(data.Snapshots))
+	for i, s := range data.Snapshots {
+		values[i] = get(s.Metadata)
+	}
+	offsets := data.Offsets()
+	intervals := Intervals(data)
+	var periods []metricPeriod
+	for _, r := range sustainedRuns(data, minDuration, func(i int) bool { return values[i] >= threshold }) {
+		window := values[r[0] : r[1]+1]
+		periods = append(periods, metricPeriod{
+			Start:    r[0],
+			End:      r[1],
+			Duration: offsets[r[1]] - offsets[r[0]] + intervals[r[1]],
+			Mean:     Mean(window),
+			Peak:     maxOf(window),
+		})
+	}
+	return periods
+}
+
+// IOWaitRule flags sustained CPU time spent waiting on I/O. Zero fields use
+// the defaults.
+type IOWaitRule struct {
+	Warning     float64       // iowait % that raises a warning, default 10
+	Critical    float64       // peak iowait % that makes it critical, default 25
+	MinDuration time.Duration // default 30s
+}
+
+func (IOWaitRule) Name() string { return "iowait" }
+
+func (r IOWaitRule) Evaluate(in RuleInput) []Finding {
+	if r.Warning <= 0 {
+		r.Warning = 10
+	}
+	if r.Critical <= 0 {
+		r.Critical = 25
+	}
+	if r.MinDuration <= 0 {
+		r.MinDuration = 30 * time.Second
+	}
+	var findings []Finding
+	for _, p := range sustainedAbove(in.Data, r.Warning, r.MinDuration, func(m parser.Metadata) float64 { return m.CPUWait }) {
+		f := Finding{
+			Severity: SeverityWarning,
+			Title:    fmt.Sprintf("High iowait: %.0f%% for %s", p.Mean, p.Duration.Round(time.Second)),
+			Explanation: fmt.Sprintf("CPUs sat idle waiting on disk or network storage for %.1f%% of the time on average, peaking at %.1f%%. "+
+				"Threads blocked on I/O (state D) are listed; check the storage latency and what those threads read or write.", p.Mean, p.Peak),
+			Start: p.Start,
+			End:   p.End,
+			Threads: topThreads(in.Data, p.Start, p.End, func(pd parser.ProcessData) float64 {
+				if pd.S == "D" {
+					return 1
+				}
+				return 0
+			}),
+		}
+		if p.Peak >= r.Critical {
+			f.Severity = SeverityCritical
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// StealRule flags CPU time taken by the hypervisor for other guests. Zero
+// fields use the defaults.
+type StealRule struct {
+	Warning     float64       // steal % that raises a warning, default 5
+	Critical    float64       // peak steal % that makes it critical, default 15
+	MinDuration time.Duration // default 30s
+}
+
+func (StealRule) Name() string { return "steal" }
+
+func (r StealRule) Evaluate(in RuleInput) []Finding {
+	if r.Warning <= 0 {
+		r.Warning = 5
+	}
+	if r.Critical <= 0 {
+		r.Critical = 15
+	}
+	if r.MinDuration <= 0 {
+		r.MinDuration = 30 * time.Second
+	}
+	var findings []Finding
+	for _, p := range sustainedAbove(in.Data, r.Warning, r.MinDuration, func(m parser.Metadata) float64 { return m.CPUSteal }) {
+		f := Finding{
+			Severity: SeverityWarning,
+			Title:    fmt.Sprintf("CPU steal: %.0f%% for %s", p.Mean, p.Duration.Round(time.Second)),
+			Explanation: fmt.Sprintf("The hypervisor withheld %.1f%% of CPU time on average, peaking at %.1f%%, so threads ran slower than their %%CPU suggests. "+
+				"The VM is contending with other guests on its host; move it or use dedicated instances.", p.Mean, p.Peak),
+			Start: p.Start,
+			End:   p.End,
+		}
+		if p.Peak >= r.Critical {
+			f.Severity = SeverityCritical
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// SwapRule flags swap in use, which makes JVM garbage collection crawl as it
+// touches swapped out heap pages. Zero fields use the defaults.
+type SwapRule struct {
+	Warning  float64 // % of swap used that raises a warning, default 10
+	Critical float64 // % of swap used that makes it critical, default 50
+	// Growth is the increase over the capture, in % of swap, that makes it
+	// critical regardless of the level, default 5.
+	Growth float64
+}
+
+func (SwapRule) Name() string { return "swap" }
+
+func (r SwapRule) Evaluate(in RuleInput) []Finding {
+	if r.Warning <= 0 {
+		r.Warning = 10
+	}
+	if r.Critical <= 0 {
+		r.Critical = 50
+	}
+	if r.Growth <= 0 {
+		r.Growth = 5
+	}
+	snaps := in.Data.Snapshots
+	start, end := -1, -1
+	var peak, total float64
+	for i, s := range snaps {
+		if s.Metadata.SwapTotal <= 0 {
+			continue
+		}
+		pct := s.Metadata.SwapUsed / s.Metadata.SwapTotal * 100
+		if pct < r.Warning {
+			continue
+		}
+		if start == -1 {
+			start = i
+		}
+		end = i
+		if s.Metadata.SwapUsed > peak {
+			peak, total = s.Metadata.SwapUsed, s.Metadata.SwapTotal
+		}
+	}
+	if start == -1 {
+		return nil
+	}
+	first, last := snaps[0].Metadata, snaps[len(snaps)-1].Metadata
+	growth := last.SwapUsed - first.SwapUsed
+	f := Finding{
+		Severity: SeverityWarning,
+		Title:    fmt.Sprintf("Swap in use: %.0f%% of %.0f MiB", peak/total*100, total),
+		Explanation: fmt.Sprintf("Swap used peaked at %.0f MiB. A JVM whose heap is partly swapped out pauses for a long time whenever GC touches those pages; "+
+			"reduce the heap or other memory use so the process fits in RAM, or disable swap.", peak),
+		Start: start,
+		End:   end,
+	}
+	if growth > 0 {
+		f.Explanation += fmt.Sprintf(" Swap grew by %.0f MiB during the capture.", growth)
+	}
+	if peak/total*100 >= r.Critical || (last.SwapTotal > 0 && growth/last.SwapTotal*100 >= r.Growth) {
+		f.Severity = SeverityCritical
+	}
+	return []Finding{f}
+}
+
+// LoadRule flags a 1 minute load average above the host's core count, i.e.
+// more runnable threads than cores. It needs the core count and stays quiet
+// without it. Zero fields use the defaults.
+type LoadRule struct {
+	Factor      float64       // load per core that raises a warning, default 1
+	Critical    float64       // peak load per core that makes it critical, default 2
+	MinDuration time.Duration // default 60s
+}
+
+func (LoadRule) Name() string { return "load" }
+
+func (r LoadRule) Evaluate(in RuleInput) []Finding {
+	if in.Cores <= 0 {
+		return nil
+	}
+	if r.Factor <= 0 {
+		r.Factor = 1
+	}
+	if r.Critical <= 0 {
+		r.Critical = 2
+	}
+	if r.MinDuration <= 0 {
+		r.MinDuration = 60 * time.Second
+	}
+	cores := float64(in.Cores)
+	var findings []Finding
+	for _, p := range sustainedAbove(in.Data, r.Factor*cores, r.MinDuration, func(m parser.Metadata) float64 { return m.LoadAvg1 }) {
+		f := Finding{
+			Severity: SeverityWarning,
+			Title:    fmt.Sprintf("Load above core count: %.1f on %d cores for %s", p.Mean, in.Cores, p.Duration.Round(time.Second)),
+			Explanation: fmt.Sprintf("The 1 minute load average stayed above %.1f, peaking at %.1f, so threads queued for a CPU. "+
+				"Load also counts threads blocked on I/O; the busiest threads of the period are listed.", r.Factor*cores, p.Peak),
+			Start:   p.Start,
+			End:     p.End,
+			Threads: topThreads(in.Data, p.Start, p.End, func(pd parser.ProcessData) float64 { return pd.CPU }),
+		}
+		if p.Peak >= r.Critical*cores {
+			f.Severity = SeverityCritical
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// ZombieRule flags zombie threads, which have exited without being reaped by
+// their parent.
+type ZombieRule struct{}
+
+func (ZombieRule) Name() string { return "zombie" }
+
+func (ZombieRule) Evaluate(in RuleInput) []Finding {
+	start, end, peak := -1, -1, 0
+	for i, s := range in.Data.Snapshots {
+		if z := s.Metadata.ThreadsZombie; z > 0 {
+			if start == -1 {
+				start = i
+			}
+			end = i
+			if z > peak {
+				peak = z
+			}
+		}
+	}
+	if start == -1 {
+		return nil
+	}
+	return []Finding{{
+		Severity: SeverityWarning,
+		Title:    fmt.Sprintf("Zombie threads: up to %d", peak),
+		Explanation: "Threads exited but were never reaped by their parent. Zombies use no CPU or memory but hold a PID; " +
+			"a growing number points to a parent process that does not wait for its children.",
+		Start: start,
+		End:   end,
+		Threads: topThreads(in.Data, start, end, func(pd parser.ProcessData) float64 {
+			if pd.S == "Z" {
+				return 1
+			}
+			return 0
+		}),
+	}}
+}
+
+// HotThreadRule flags threads pegged near 100% of a core while the host had
+// spare capacity, which caps throughput at what one core can do.
+type HotThreadRule struct {
+	Config SaturationConfig // a zero Cores is taken from RuleInput
+}
+
+func (HotThreadRule) Name() string { return "hot-thread" }
+
+func (r HotThreadRule) Evaluate(in RuleInput) []Finding {
+	cfg := r.Config
+	if cfg.Cores <= 0 {
+		cfg.Cores = in.Cores
+	}
+	var findings []Finding
+	for _, p := range DetectSaturation(in.Data, cfg).Pegged {
+		findings = append(findings, Finding{
+			Severity: SeverityWarning,
+			Title:    fmt.Sprintf("Hot thread: %s at %.0f%% for %s", p.Command, p.MeanCPU, p.Duration.Round(time.Second)),
+			Explanation: fmt.Sprintf("A single thread used a full core while the host was only %.0f%% busy, so work funneled through it is capped at one core. "+
+				"Take a thread dump during this period to see what it runs.", p.HostBusy),
+			Start:   p.Start,
+			End:     p.End,
+			Threads: []string{fmt.Sprintf("%s (%d)", p.Command, p.TID)},
+		})
+	}
+	return findings
+}
@@ -0,0 +1,161 @@
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// findingsCapture has a snapshot every 10s for 100s on a host 20% busy and
+// lets each test shape the metadata and threads of every snapshot.
+func findingsCapture(shape func(i int, s *parser.Snapshot)) parser.ReportData {
+	return capture(every(10, 10), func(i int, s *parser.Snapshot) {
+		s.Metadata.CPUIdle = 80
+		s.Metadata.CPUUser = 20
+		shape(i, s)
+	})
+}
+
+func TestIOWaitRule(t *testing.T) {
+	data := findingsCapture(func(i int, s *parser.Snapshot) {
+		s.Processes = []parser.ProcessData{{PID: 1, Command: "writer", S: "S"}, {PID: 2, Command: "reader", S: "S"}}
+		if i >= 3 && i <= 6 {
+			s.Metadata.CPUWait = 15
+			s.Processes[0].S = "D"
+			if i == 5 {
+				s.Metadata.CPUWait = 30
+				s.Processes[1].S = "D"
+			}
+		}
+	})
+	findings := IOWaitRule{}.Evaluate(RuleInput{Data: data})
+	if len(findings) != 1 {
+		t.Fatalf("expected one finding, got %+v", findings)
+	}
+	f := findings[0]
+	if f.Severity != SeverityCritical || f.Start != 3 || f.End != 6 || !strings.Contains(f.Title, "for 40s") {
+		t.Errorf("unexpected finding: %+v", f)
+	}
+	if want := []string{"writer (1)", "reader (2)"}; !reflect.DeepEqual(f.Threads, want) {
+		t.Errorf("expected threads %v, got %v", want, f.Threads)
+	}
+
+	// too short to matter
+	brief := findingsCapture(func(i int, s *parser.Snapshot) {
+		if i == 3 {
+			s.Metadata.CPUWait = 50
+		}
+	})
+	if findings := (IOWaitRule{}).Evaluate(RuleInput{Data: brief}); len(findings) != 0 {
+		t.Errorf("expected a single spike to be ignored, got %+v", findings)
+	}
+}
+
+func TestStealRule(t *testing.T) {
+	data := findingsCapture(func(i int, s *parser.Snapshot) { s.Metadata.CPUSteal = 6 })
+	findings := StealRule{}.Evaluate(RuleInput{Data: data})
+	if len(findings) != 1 || findings[0].Severity != SeverityWarning || findings[0].Start != 0 || findings[0].End != 9 {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+}
+
+func TestSwapRule(t *testing.T) {
+	steady := findingsCapture(func(i int, s *parser.Snapshot) {
+		s.Metadata.SwapTotal = 1000
+		s.Metadata.SwapUsed = 200
+	})
+	findings := SwapRule{}.Evaluate(RuleInput{Data: steady})
+	if len(findings) != 1 || findings[0].Severity != SeverityWarning || !strings.Contains(findings[0].Title, "20% of 1000 MiB") {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+
+	growing := findingsCapture(func(i int, s *parser.Snapshot) {
+		s.Metadata.SwapTotal = 1000
+		s.Metadata.SwapUsed = 150 + float64(i)*10
+	})
+	findings = SwapRule{}.Evaluate(RuleInput{Data: growing})
+	if len(findings) != 1 || findings[0].Severity != SeverityCritical || !strings.Contains(findings[0].Explanation, "grew by 90 MiB") {
+		t.Errorf("expected growing swap to be critical, got %+v", findings)
+	}
+
+	none := findingsCapture(func(i int, s *parser.Snapshot) { s.Metadata.SwapTotal = 1000 })
+	if findings := (SwapRule{}).Evaluate(RuleInput{Data: none}); len(findings) != 0 {
+		t.Errorf("expected no findings, got %+v", findings)
+	}
+}
+
+func TestLoadRule(t *testing.T) {
+	data := findingsCapture(func(i int, s *parser.Snapshot) {
+		s.Metadata.LoadAvg1 = 2
+		if i >= 2 {
+			s.Metadata.LoadAvg1 = 6
+		}
+		s.Processes = []parser.ProcessData{{PID: 1, Command: "busy", CPU: 90}, {PID: 2, Command: "idle"}}
+	})
+	if findings := (LoadRule{}).Evaluate(RuleInput{Data: data}); len(findings) != 0 {
+		t.Errorf("expected no findings without a core count, got %+v", findings)
+	}
+	findings := LoadRule{}.Evaluate(RuleInput{Data: data, Cores: 4})
+	if len(findings) != 1 {
+		t.Fatalf("expected one finding, got %+v", findings)
+	}
+	if f := findings[0]; f.Severity != SeverityWarning || f.Start != 2 || f.End != 9 || !reflect.DeepEqual(f.Threads, []string{"busy (1)"}) {
+		t.Errorf("unexpected finding: %+v", f)
+	}
+	if findings := (LoadRule{}).Evaluate(RuleInput{Data: data, Cores: 2}); len(findings) != 1 || findings[0].Severity != SeverityCritical {
+		t.Errorf("expected load of 3x the cores to be critical, got %+v", findings)
+	}
+}
+
+func TestZombieRule(t *testing.T) {
+	data := findingsCapture(func(i int, s *parser.Snapshot) {
+		if i == 4 || i == 6 {
+			s.Metadata.ThreadsZombie = i / 2
+			s.Processes = []parser.ProcessData{{PID: 7, Command: "defunct", S: "Z"}}
+		}
+	})
+	findings := ZombieRule{}.Evaluate(RuleInput{Data: data})
+	if len(findings) != 1 {
+		t.Fatalf("expected one finding, got %+v", findings)
+	}
+	if f := findings[0]; f.Start != 4 || f.End != 6 || f.Title != "Zombie threads: up to 3" || !reflect.DeepEqual(f.Threads, []string{"defunct (7)"}) {
+		t.Errorf("unexpected finding: %+v", f)
+	}
+}
+
+func TestHotThreadRule(t *testing.T) {
+	data := findingsCapture(func(i int, s *parser.Snapshot) {
+		s.Processes = []parser.ProcessData{{PID: 9, Command: "single", CPU: 99}}
+	})
+	findings := HotThreadRule{}.Evaluate(RuleInput{Data: data, Cores: 8})
+	if len(findings) != 1 || !reflect.DeepEqual(findings[0].Threads, []string{"single (9)"}) || !strings.Contains(findings[0].Title, "for 1m40s") {
+		t.Errorf("unexpected findings: %+v", findings)
+	}
+}
+
+type fixedRule []Finding
+
+func (fixedRule) Name() string                   { return "fixed" }
+func (r fixedRule) Evaluate(RuleInput) []Finding { return r }
+
+func TestRunRules(t *testing.T) {
+	findings := RunRules(RuleInput{}, []Rule{
+		fixedRule{{Title: "late warning", Severity: SeverityWarning, Start: 5}, {Title: "info", Severity: SeverityInfo}},
+		fixedRule{{Title: "critical", Severity: SeverityCritical, Start: 9}, {Title: "early warning", Severity: SeverityWarning, Start: 1, Rule: "custom"}},
+	})
+	var titles []string
+	for _, f := range findings {
+		titles = append(titles, f.Title)
+	}
+	if want := []string{"critical", "early warning", "late warning", "info"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("expected order %v, got %v", want, titles)
+	}
+	if findings[0].Rule != "fixed" || findings[1].Rule != "custom" {
+		t.Errorf("expected rule names to be filled in only when missing, got %+v", findings)
+	}
+	if SeverityCritical.String() != "critical" || SeverityInfo.String() != "info" {
+		t.Error("unexpected severity names")
+	}
+}
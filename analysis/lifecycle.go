@@ -0,0 +1,182 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// Lifecycle event kinds. top only lists the busiest threads, so a thread
+// appearing or disappearing may also have moved into or out of that list.
+const (
+	EventAppeared    = "appeared"
+	EventDisappeared = "disappeared"
+)
+
+// LifecycleEvent records a thread showing up after the first snapshot or
+// vanishing before the last one.
+type LifecycleEvent struct {
+	Index   int // snapshot index where the thread appeared or was last seen
+	TID     int
+	Command string
+	Pool    string
+	Kind    string
+}
+
+// PoolChurn counts lifecycle events for one pool.
+type PoolChurn struct {
+	Pool         string
+	Appeared     int
+	Disappeared  int
+	ShortLived   int // threads that appeared and disappeared within the threshold
+	MeanLifetime time.Duration
+	Churning     bool // ShortLived reached the churn threshold
+}
+
+// LeakAssessment describes the trend of the total thread count.
+type LeakAssessment struct {
+	First, Last       int     // ThreadsTotal in the first and last snapshot
+	SlopePerMinute    float64 // fitted growth in threads per minute
+	R2                float64
+	MonotonicFraction float64 // share of steps where the count did not drop
+	Suspected         bool
+}
+
+// Lifecycle is the result of AnalyzeLifecycle.
+type Lifecycle struct {
+	Events []LifecycleEvent
+	Churn  []PoolChurn // pools with events, most short-lived threads first
+	Leak   LeakAssessment
+}
+
+// LifecycleConfig tunes lifecycle analysis. Zero fields fall back to defaults.
+type LifecycleConfig struct {
+	// ShortLived is the longest lifetime counted as churn, default 10s.
+	ShortLived time.Duration
+	// ChurnThreshold is how many short-lived threads flag a pool, default 5.
+	ChurnThreshold int
+	// MinLeakGrowth is the net thread count growth needed to suspect a
+	// leak, default 10.
+	MinLeakGrowth int
+}
+
+// AnalyzeLifecycle reports when threads appear and disappear, flags pools
+// that keep creating short-lived threads, and checks ThreadsTotal for the
+// steady growth that suggests a thread leak.
+func AnalyzeLifecycle(data parser.ReportData, n *Normalizer, cfg LifecycleConfig) Lifecycle {
+	if n == nil {
+		n = DefaultNormalizer()
+	}
+	if cfg.ShortLived <= 0 {
+		cfg.ShortLived = 10 * time.Second
+	}
+	if cfg.ChurnThreshold <= 0 {
+		cfg.ChurnThreshold = 5
+	}
+	if cfg.MinLeakGrowth <= 0 {
+		cfg.MinLeakGrowth = 10
+	}
+
+	var result Lifecycle
+	last := len(data.Snapshots) - 1
+	offsets := data.Offsets()
+	intervals := Intervals(data)
+	churn := make(map[string]*PoolChurn)
+	lifetimes := make(map[string][]time.Duration)
+
+	for _, t := range Threads(data) {
+		first, lastSeen := -1, -1
+		for i, present := range t.Present {
+			if present {
+				if first == -1 {
+					first = i
+				}
+				lastSeen = i
+			}
+		}
+		pool := n.Normalize(t.Command)
+		appeared := first > 0
+		disappeared := lastSeen < last
+		if !appeared && !disappeared {
+			continue
+		}
+
+		c := churn[pool]
+		if c == nil {
+			c = &PoolChurn{Pool: pool}
+			churn[pool] = c
+		}
+		if appeared {
+			c.Appeared++
+			result.Events = append(result.Events, LifecycleEvent{Index: first, TID: t.TID, Command: t.Command, Pool: pool, Kind: EventAppeared})
+		}
+		if disappeared {
+			c.Disappeared++
+			result.Events = append(result.Events, LifecycleEvent{Index: lastSeen, TID: t.TID, Command: t.Command, Pool: pool, Kind: EventDisappeared})
+		}
+		if appeared && disappeared {
+			lifetime := offsets[lastSeen] - offsets[first] + intervals[lastSeen]
+			lifetimes[pool] = append(lifetimes[pool], lifetime)
+			if lifetime <= cfg.ShortLived {
+				c.ShortLived++
+			}
+		}
+	}
+
+	for pool, c := range churn {
+		if l := lifetimes[pool]; len(l) > 0 {
+			var total time.Duration
+			for _, d := range l {
+				total += d
+			}
+			c.MeanLifetime = total / time.Duration(len(l))
+		}
+		c.Churning = c.ShortLived >= cfg.ChurnThreshold
+		result.Churn = append(result.Churn, *c)
+	}
+	sort.SliceStable(result.Events, func(i, j int) bool {
+		return result.Events[i].Index < result.Events[j].Index
+	})
+	sort.Slice(result.Churn, func(i, j int) bool {
+		a, b := result.Churn[i], result.Churn[j]
+		if a.ShortLived != b.ShortLived {
+			return a.ShortLived > b.ShortLived
+		}
+		if a.Appeared+a.Disappeared != b.Appeared+b.Disappeared {
+			return a.Appeared+a.Disappeared > b.Appeared+b.Disappeared
+		}
+		return a.Pool < b.Pool
+	})
+
+	result.Leak = assessLeak(data, offsets, cfg.MinLeakGrowth)
+	return result
+}
+
+// assessLeak fits a line through ThreadsTotal. A leak is suspected when the
+// count grows by at least minGrowth, rarely drops, and the fit is good.
+func assessLeak(data parser.ReportData, offsets []time.Duration, minGrowth int) LeakAssessment {
+	if len(data.Snapshots) < 2 {
+		return LeakAssessment{}
+	}
+	x := make([]float64, len(data.Snapshots))
+	y := make([]float64, len(data.Snapshots))
+	nonDecreasing := 0
+	for i, s := range data.Snapshots {
+		x[i] = offsets[i].Minutes()
+		y[i] = float64(s.Metadata.ThreadsTotal)
+		if i > 0 && s.Metadata.ThreadsTotal >= data.Snapshots[i-1].Metadata.ThreadsTotal {
+			nonDecreasing++
+		}
+	}
+	slope, _, r2 := LinearFit(x, y)
+	leak := LeakAssessment{
+		First:             data.Snapshots[0].Metadata.ThreadsTotal,
+		Last:              data.Snapshots[len(data.Snapshots)-1].Metadata.ThreadsTotal,
+		SlopePerMinute:    slope,
+		R2:                r2,
+		MonotonicFraction: float64(nonDecreasing) / float64(len(data.Snapshots)-1),
+	}
+	leak.Suspected = leak.Last-leak.First >= minGrowth && slope > 0 && leak.MonotonicFraction >= 0.9 && r2 >= 0.8
+	return leak
+}
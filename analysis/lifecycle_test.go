@@ -0,0 +1,90 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestAnalyzeLifecycle_Events(t *testing.T) {
+	data := capture(every(4, 2), listed(
+		[]parser.ProcessData{{PID: 1, Command: "main"}, {PID: 2, Command: "old-1"}},
+		[]parser.ProcessData{{PID: 1, Command: "main"}, {PID: 3, Command: "worker-1"}},
+		[]parser.ProcessData{{PID: 1, Command: "main"}, {PID: 3, Command: "worker-1"}},
+		[]parser.ProcessData{{PID: 1, Command: "main"}},
+	))
+	lc := AnalyzeLifecycle(data, nil, LifecycleConfig{})
+	want := []LifecycleEvent{
+		{Index: 0, TID: 2, Command: "old-1", Pool: "old", Kind: EventDisappeared},
+		{Index: 1, TID: 3, Command: "worker-1", Pool: "worker", Kind: EventAppeared},
+		{Index: 2, TID: 3, Command: "worker-1", Pool: "worker", Kind: EventDisappeared},
+	}
+	if len(lc.Events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), lc.Events)
+	}
+	for i := range want {
+		if lc.Events[i] != want[i] {
+			t.Errorf("event %d: expected %+v, got %+v", i, want[i], lc.Events[i])
+		}
+	}
+
+	if len(lc.Churn) != 2 || lc.Churn[0].Pool != "worker" {
+		t.Fatalf("expected the worker pool first, got %+v", lc.Churn)
+	}
+	worker := lc.Churn[0]
+	if worker.ShortLived != 1 || worker.MeanLifetime != 4*time.Second || worker.Churning {
+		t.Errorf("unexpected worker churn: %+v", worker)
+	}
+}
+
+func TestAnalyzeLifecycle_Churning(t *testing.T) {
+	data := capture(every(8, 1), func(i int, s *parser.Snapshot) {
+		// each snapshot has a fresh short-lived thread
+		s.Processes = []parser.ProcessData{{PID: 1, Command: "main"}, {PID: 100 + i, Command: "task-" + string(rune('a'+i))}}
+	})
+	lc := AnalyzeLifecycle(data, nil, LifecycleConfig{ChurnThreshold: 3})
+	var tasks *PoolChurn
+	for i := range lc.Churn {
+		if strings.HasPrefix(lc.Churn[i].Pool, "task-") {
+			tasks = &lc.Churn[i]
+		}
+	}
+	// each task name is its own pool, so none reaches the threshold
+	if tasks == nil || tasks.Churning {
+		t.Fatalf("expected per-name task pools below the threshold, got %+v", lc.Churn)
+	}
+
+	n, err := NewNormalizer(PoolConfig{Rules: []PoolRule{{Pattern: "^task-", Pool: "tasks"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc = AnalyzeLifecycle(data, n, LifecycleConfig{ChurnThreshold: 3})
+	if lc.Churn[0].Pool != "tasks" || !lc.Churn[0].Churning || lc.Churn[0].ShortLived != 6 {
+		t.Errorf("expected the tasks pool to be churning, got %+v", lc.Churn[0])
+	}
+}
+
+func TestAnalyzeLifecycle_Leak(t *testing.T) {
+	threads := func(count func(i int) int) parser.ReportData {
+		return capture(every(30, 2), func(i int, s *parser.Snapshot) {
+			s.Processes = []parser.ProcessData{{PID: 1, Command: "main"}}
+			s.Metadata.ThreadsTotal = count(i)
+		})
+	}
+	growing := threads(func(i int) int { return 100 + i })
+	flat := threads(func(i int) int { return 100 + (i%2)*20 })
+
+	leak := AnalyzeLifecycle(growing, nil, LifecycleConfig{}).Leak
+	if !leak.Suspected || leak.First != 100 || leak.Last != 129 || leak.MonotonicFraction != 1 {
+		t.Errorf("expected a suspected leak, got %+v", leak)
+	}
+	if leak.SlopePerMinute < 29 || leak.SlopePerMinute > 31 {
+		t.Errorf("expected about 30 threads/min, got %f", leak.SlopePerMinute)
+	}
+
+	if leak := AnalyzeLifecycle(flat, nil, LifecycleConfig{}).Leak; leak.Suspected {
+		t.Errorf("did not expect a leak for an oscillating count: %+v", leak)
+	}
+}
@@ -0,0 +1,145 @@
+package analysis
+
+import (
+	"math"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// Memory metrics analyzed by MemoryTrends
+const (
+	MetricMemUsed    = "Mem used"
+	MetricMemAvail   = "Mem available"
+	MetricSwapUsed   = "Swap used"
+	MetricProcessRES = "Process RES"
+)
+
+// MemoryTrend is a linear fit of one memory metric over the capture, in MiB.
+type MemoryTrend struct {
+	Metric           string
+	First, Last      float64
+	SlopePerMinute   float64
+	R2               float64
+	Limit            float64       // value at which memory is exhausted; 0 when unknown
+	Fit              []float64     // fitted value per snapshot
+	Significant      bool          // the fit is good and the change is large enough to matter
+	TimeToExhaustion time.Duration // projected from the last snapshot; 0 when not heading towards Limit
+}
+
+// MemoryTrendConfig tunes the trend analysis. Zero fields fall back to
+// defaults.
+type MemoryTrendConfig struct {
+	// MinR2 is the goodness of fit needed for a significant trend, default 0.7.
+	MinR2 float64
+	// MinChange is the fitted change over the capture, as a fraction of the
+	// limit (or of the first value when there is none), needed for a
+	// significant trend, default 0.01.
+	MinChange float64
+}
+
+// memorySeries is one metric fed into MemoryTrends.
+type memorySeries struct {
+	name      string
+	values    []float64
+	limit     float64
+	declining bool // exhausted when the value reaches 0 rather than the limit
+}
+
+// ProcessRES returns the captured process's resident memory per snapshot in
+// MiB. Threads share their process's memory, so top -H prints the same RES on
+// each of them; the largest one listed is used.
+func ProcessRES(data parser.ReportData) []float64 {
+	res := make([]float64, len(data.Snapshots))
+	for i, s := range data.Snapshots {
+		for _, p := range s.Processes {
+			if v, err := parser.ParseMemoryMiB(p.RES); err == nil && v > res[i] {
+				res[i] = v
+			}
+		}
+	}
+	return res
+}
+
+// MemoryTrends fits a line through used and available memory, swap used and
+// the process RES, and projects when each would run out if the trend
+// continued. Free memory is not projected: Linux fills it with page cache, so
+// it falls on a healthy host. Available memory is left out when top printed no
+// "avail Mem", and process RES when the capture has no RES column values.
+func MemoryTrends(data parser.ReportData, cfg MemoryTrendConfig) []MemoryTrend {
+	if cfg.MinR2 <= 0 {
+		cfg.MinR2 = 0.7
+	}
+	if cfg.MinChange <= 0 {
+		cfg.MinChange = 0.01
+	}
+	n := len(data.Snapshots)
+	if n < 3 {
+		return nil
+	}
+	metric := func(get func(parser.Metadata) float64) []float64 {
+		values := make([]float64, n)
+		for i, s := range data.Snapshots {
+			values[i] = get(s.Metadata)
+		}
+		return values
+	}
+	memTotal := data.Snapshots[n-1].Metadata.MemTotal
+	swapTotal := data.Snapshots[n-1].Metadata.SwapTotal
+
+	x := make([]float64, n)
+	for i, o := range data.Offsets() {
+		x[i] = o.Minutes()
+	}
+
+	inputs := []memorySeries{
+		{MetricMemUsed, metric(func(m parser.Metadata) float64 { return m.MemUsed }), memTotal, false},
+		{MetricSwapUsed, metric(func(m parser.Metadata) float64 { return m.SwapUsed }), swapTotal, false},
+	}
+	if avail := metric(func(m parser.Metadata) float64 { return m.MemAvail }); sum(avail) > 0 {
+		inputs = append(inputs, memorySeries{MetricMemAvail, avail, memTotal, true})
+	}
+	if res := ProcessRES(data); sum(res) > 0 {
+		inputs = append(inputs, memorySeries{MetricProcessRES, res, memTotal, false})
+	}
+
+	var trends []MemoryTrend
+	for _, in := range inputs {
+		slope, intercept, r2 := LinearFit(x, in.values)
+		t := MemoryTrend{
+			Metric:         in.name,
+			First:          in.values[0],
+			Last:           in.values[n-1],
+			SlopePerMinute: slope,
+			R2:             r2,
+			Limit:          in.limit,
+			Fit:            make([]float64, n),
+		}
+		for i := range x {
+			t.Fit[i] = slope*x[i] + intercept
+		}
+
+		scale := in.limit
+		if scale <= 0 {
+			scale = math.Abs(t.First)
+		}
+		change := math.Abs(slope * (x[n-1] - x[0]))
+		t.Significant = r2 >= cfg.MinR2 && scale > 0 && change >= cfg.MinChange*scale
+
+		// project from the fitted end point so one noisy last sample does not
+		// move the estimate
+		end := t.Fit[n-1]
+		var minutes float64
+		switch {
+		case in.declining && slope < 0:
+			minutes = end / -slope
+		case !in.declining && slope > 0 && in.limit > 0:
+			minutes = (in.limit - end) / slope
+		}
+		if t.Significant && minutes > 0 {
+			t.TimeToExhaustion = time.Duration(minutes * float64(time.Minute))
+		}
+		trends = append(trends, t)
+	}
+	return trends
+}
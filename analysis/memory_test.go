@@ -0,0 +1,79 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// memoryData returns 11 snapshots a minute apart where used memory and RES
+// grow by 100 MiB/min out of 16000 MiB, available memory shrinks as fast and
+// swap stays flat. Free memory falls twice as fast as page cache fills it.
+func memoryData() parser.ReportData {
+	return capture(every(11, 60), func(i int, s *parser.Snapshot) {
+		used := 4000 + float64(i)*100
+		s.Metadata = parser.Metadata{
+			MemTotal: 16000, MemUsed: used, MemFree: 16000 - 2*used, MemAvail: 16000 - used,
+			SwapTotal: 2000, SwapUsed: 10 + float64(i%2),
+		}
+		s.Processes = []parser.ProcessData{
+			{PID: 1, RES: fmt.Sprintf("%dm", 1000+i*100)},
+			{PID: 2, RES: fmt.Sprintf("%dm", 1000+i*100)},
+		}
+	})
+}
+
+func TestMemoryTrends(t *testing.T) {
+	trends := MemoryTrends(memoryData(), MemoryTrendConfig{})
+	if len(trends) != 4 {
+		t.Fatalf("expected 4 trends, got %+v", trends)
+	}
+	byMetric := make(map[string]MemoryTrend)
+	for _, tr := range trends {
+		byMetric[tr.Metric] = tr
+	}
+
+	used := byMetric[MetricMemUsed]
+	if !used.Significant || used.SlopePerMinute < 99.9 || used.SlopePerMinute > 100.1 {
+		t.Errorf("expected used memory to grow 100 MiB/min, got %+v", used)
+	}
+	// 16000 - 5000 MiB left at 100 MiB/min
+	if d := used.TimeToExhaustion - 110*time.Minute; d < -time.Second || d > time.Second {
+		t.Errorf("expected about 110m to exhaustion, got %v", used.TimeToExhaustion)
+	}
+	if avail := byMetric[MetricMemAvail]; avail.TimeToExhaustion != used.TimeToExhaustion {
+		t.Errorf("available memory should run out when used memory does, got %v", avail.TimeToExhaustion)
+	}
+	if swap := byMetric[MetricSwapUsed]; swap.Significant || swap.TimeToExhaustion != 0 {
+		t.Errorf("flat swap should not be significant: %+v", swap)
+	}
+	if res := byMetric[MetricProcessRES]; res.First != 1000 || res.Last != 2000 || !res.Significant {
+		t.Errorf("unexpected RES trend: %+v", res)
+	}
+}
+
+func TestMemoryTrends_NoAvail(t *testing.T) {
+	data := memoryData()
+	for i := range data.Snapshots {
+		data.Snapshots[i].Metadata.MemAvail = 0
+	}
+	for _, tr := range MemoryTrends(data, MemoryTrendConfig{}) {
+		if tr.Metric == MetricMemAvail {
+			t.Errorf("did not expect an available memory trend without avail Mem")
+		}
+	}
+}
+
+func TestMemoryTrends_NoRES(t *testing.T) {
+	data := memoryData()
+	for i := range data.Snapshots {
+		data.Snapshots[i].Processes = nil
+	}
+	for _, tr := range MemoryTrends(data, MemoryTrendConfig{}) {
+		if tr.Metric == MetricProcessRES {
+			t.Errorf("did not expect a RES trend without RES values")
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// Periodicity series kinds
+const (
+	PeriodicitySystem = "system"
+	PeriodicityPool   = "pool"
+)
+
+// PeriodicityConfig tunes period detection. Zero fields fall back to
+// defaults.
+type PeriodicityConfig struct {
+	// MinStrength is the autocorrelation a period needs, default 0.3.
+	MinStrength float64
+	// MaxPools limits the pools checked to the busiest ones, default 10.
+	MaxPools int
+}
+
+func (c PeriodicityConfig) withDefaults() PeriodicityConfig {
+	if c.MinStrength <= 0 {
+		c.MinStrength = 0.3
+	}
+	if c.MaxPools <= 0 {
+		c.MaxPools = 10
+	}
+	return c
+}
+
+// minPeriodicitySnapshots is the shortest capture checked; a period needs to
+// repeat at least three times and span at least two snapshots.
+const minPeriodicitySnapshots = 12
+
+// Periodicity is a recurring pattern in one series.
+type Periodicity struct {
+	Kind   string
+	Series string
+	Lag    int // period in snapshots
+	Period time.Duration
+	// Strength is the autocorrelation of the series at Lag, in (0, 1].
+	Strength float64
+	// Peaks are the snapshots where the pattern recurs.
+	Peaks []int
+	// Threads are the threads whose CPU rises most at the peaks, as
+	// "command (tid)".
+	Threads []string
+}
+
+// Autocorrelation returns the autocorrelation of values at lags 0..maxLag.
+// It uses the biased estimator, which damps long lags whose estimates rest on
+// few pairs. A constant series has no autocorrelation and yields nil.
+func Autocorrelation(values []float64, maxLag int) []float64 {
+	n := len(values)
+	if maxLag >= n {
+		maxLag = n - 1
+	}
+	mean := Mean(values)
+	var denom float64
+	for _, v := range values {
+		denom += (v - mean) * (v - mean)
+	}
+	if denom == 0 || maxLag < 0 {
+		return nil
+	}
+	acf := make([]float64, maxLag+1)
+	for k := 0; k <= maxLag; k++ {
+		var sum float64
+		for i := 0; i+k < n; i++ {
+			sum += (values[i] - mean) * (values[i+k] - mean)
+		}
+		acf[k] = sum / denom
+	}
+	return acf
+}
+
+// dominantLag picks the strongest local maximum of the autocorrelation from
+// lag 2 on. Multiples of the true period peak as well, so the shortest lag
+// within 90% of the strongest wins.
+func dominantLag(acf []float64, minStrength float64) (int, float64) {
+	var peaks []int
+	best := 0.0
+	for k := 2; k+1 < len(acf); k++ {
+		if acf[k] >= minStrength && acf[k] > acf[k-1] && acf[k] >= acf[k+1] {
+			peaks = append(peaks, k)
+			if acf[k] > best {
+				best = acf[k]
+			}
+		}
+	}
+	for _, k := range peaks {
+		if acf[k] >= 0.9*best {
+			return k, acf[k]
+		}
+	}
+	return 0, 0
+}
+
+// recurrencePeaks returns the snapshots that are the highest within half a
+// period either side and above the series mean.
+func recurrencePeaks(values []float64, lag int) []int {
+	mean := Mean(values)
+	var peaks []int
+	half := lag / 2
+	for i, v := range values {
+		if v <= mean {
+			continue
+		}
+		top := true
+		for j := i - half; j <= i+half && top; j++ {
+			if j >= 0 && j < len(values) && j != i && (values[j] > v || (values[j] == v && j < i)) {
+				top = false
+			}
+		}
+		if top {
+			peaks = append(peaks, i)
+		}
+	}
+	return peaks
+}
+
+// DetectPeriodicity looks for recurring patterns, such as scheduled jobs or
+// GC cycles, in total CPU, the 1 minute load and the CPU of the busiest pools
+// using their autocorrelation. Results are ordered by strength.
+func DetectPeriodicity(data parser.ReportData, n *Normalizer, cfg PeriodicityConfig) []Periodicity {
+	cfg = cfg.withDefaults()
+	count := len(data.Snapshots)
+	if count < minPeriodicitySnapshots {
+		return nil
+	}
+	offsets := data.Offsets()
+	interval := offsets[count-1] / time.Duration(count-1)
+	threads := Threads(data)
+
+	var out []Periodicity
+	check := func(kind, name string, values []float64) {
+		lag, strength := dominantLag(Autocorrelation(values, count/3), cfg.MinStrength)
+		if lag == 0 {
+			return
+		}
+		p := Periodicity{
+			Kind:     kind,
+			Series:   name,
+			Lag:      lag,
+			Period:   (interval * time.Duration(lag)).Round(time.Second),
+			Strength: strength,
+			Peaks:    recurrencePeaks(values, lag),
+		}
+		p.Threads = peakContributors(threads, p.Peaks)
+		out = append(out, p)
+	}
+
+	for _, m := range SystemMetrics {
+		if m.Name == MetricTotalCPU || m.Name == MetricLoad1 {
+			check(PeriodicitySystem, m.Name, m.Values(data))
+		}
+	}
+	for i, pool := range Pools(data, n) {
+		if i == cfg.MaxPools {
+			break
+		}
+		check(PeriodicityPool, pool.Name, pool.CPU)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Strength > out[j].Strength })
+	return out
+}
+
+// peakContributors ranks threads by how much higher their CPU is at the
+// peaks than on average, returning up to maxFindingThreads that rise by at
+// least a percent.
+func peakContributors(threads []ThreadSeries, peaks []int) []string {
+	if len(peaks) == 0 {
+		return nil
+	}
+	type rise struct {
+		name  string
+		delta float64
+	}
+	var rises []rise
+	for _, t := range threads {
+		var atPeaks float64
+		for _, i := range peaks {
+			atPeaks += t.CPU[i]
+		}
+		if d := atPeaks/float64(len(peaks)) - Mean(t.CPU); d >= 1 {
+			rises = append(rises, rise{fmt.Sprintf("%s (%d)", t.Command, t.TID), d})
+		}
+	}
+	sort.SliceStable(rises, func(i, j int) bool { return rises[i].delta > rises[j].delta })
+	var out []string
+	for i, r := range rises {
+		if i == maxFindingThreads {
+			break
+		}
+		out = append(out, r.name)
+	}
+	return out
+}
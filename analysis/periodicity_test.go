@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestAutocorrelation(t *testing.T) {
+	acf := Autocorrelation([]float64{1, 0, 1, 0, 1, 0, 1, 0}, 3)
+	if len(acf) != 4 || acf[0] != 1 || acf[1] >= 0 || acf[2] <= 0 {
+		t.Errorf("unexpected autocorrelation: %v", acf)
+	}
+	if acf := Autocorrelation([]float64{2, 2, 2}, 2); acf != nil {
+		t.Errorf("expected nil for a constant series, got %v", acf)
+	}
+}
+
+func TestDominantLag_PrefersShortestPeriod(t *testing.T) {
+	acf := []float64{1, 0.1, -0.5, 0.1, 0.8, 0.1, -0.4, 0.1, 0.85, 0.1}
+	if lag, strength := dominantLag(acf, 0.3); lag != 4 || strength != 0.8 {
+		t.Errorf("expected lag 4 at 0.8, got %d at %v", lag, strength)
+	}
+	if lag, _ := dominantLag([]float64{1, 0.5, 0.2, 0.1, 0.05}, 0.3); lag != 0 {
+		t.Errorf("expected no period in a decaying series, got %d", lag)
+	}
+}
+
+// periodicCapture has a snapshot every 10s over 5 minutes; a refresh thread
+// spikes every 60s while a worker stays flat.
+func periodicCapture() parser.ReportData {
+	return capture(every(30, 10), func(i int, s *parser.Snapshot) {
+		refresh := 1.0
+		if i%6 == 2 {
+			refresh = 80
+		}
+		s.Processes = []parser.ProcessData{
+			{PID: 1, Command: "metadata-refresh", CPU: refresh},
+			{PID: 2, Command: "worker-1", CPU: 20 + float64(i%2)},
+		}
+		s.Metadata.CPUUser = 10 + refresh/4
+		s.Metadata.LoadAvg1 = 2
+	})
+}
+
+func TestDetectPeriodicity(t *testing.T) {
+	periods := DetectPeriodicity(periodicCapture(), nil, PeriodicityConfig{})
+	var total *Periodicity
+	for i, p := range periods {
+		if p.Series == MetricLoad1 {
+			t.Errorf("expected the flat load to have no period, got %+v", p)
+		}
+		if p.Series == MetricTotalCPU {
+			total = &periods[i]
+		}
+	}
+	if total == nil {
+		t.Fatalf("expected a period in total CPU, got %+v", periods)
+	}
+	if total.Lag != 6 || total.Period != time.Minute || total.Strength < 0.5 {
+		t.Errorf("expected a strong 1m period, got %+v", total)
+	}
+	if want := []int{2, 8, 14, 20, 26}; !reflect.DeepEqual(total.Peaks, want) {
+		t.Errorf("expected peaks %v, got %v", want, total.Peaks)
+	}
+	if want := []string{"metadata-refresh (1)"}; !reflect.DeepEqual(total.Threads, want) {
+		t.Errorf("expected contributors %v, got %v", want, total.Threads)
+	}
+
+	short := parser.ReportData{Snapshots: periodicCapture().Snapshots[:minPeriodicitySnapshots-1]}
+	if got := DetectPeriodicity(short, nil, PeriodicityConfig{}); got != nil {
+		t.Errorf("expected a short capture to be skipped, got %+v", got)
+	}
+}
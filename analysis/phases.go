@@ -0,0 +1,165 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// PhaseConfig tunes change-point detection. Zero fields fall back to
+// defaults.
+type PhaseConfig struct {
+	// MinSnapshots is the shortest phase, default 5.
+	MinSnapshots int
+	// MaxPhases caps the number of phases, default 8.
+	MaxPhases int
+	// Penalty scales the cost of adding a change point; higher values find
+	// fewer phases. Default 3.
+	Penalty float64
+}
+
+func (c PhaseConfig) withDefaults() PhaseConfig {
+	if c.MinSnapshots <= 0 {
+		c.MinSnapshots = 5
+	}
+	if c.MaxPhases <= 0 {
+		c.MaxPhases = 8
+	}
+	if c.Penalty <= 0 {
+		c.Penalty = 3
+	}
+	return c
+}
+
+// Phase is a stretch of the capture with steady system behaviour.
+type Phase struct {
+	Start    int // first snapshot
+	End      int // last snapshot
+	Duration time.Duration
+	// Means holds the mean of each of SystemMetrics over the phase, in the
+	// same order.
+	Means      []float64
+	TopThreads []PhaseThread // busiest threads of the phase by CPU time
+}
+
+// PhaseThread is one of a phase's busiest threads.
+type PhaseThread struct {
+	TID     int
+	Command string
+	// MeanCPU is the thread's %CPU averaged over every snapshot of the
+	// phase, counting snapshots it was not listed in as idle.
+	MeanCPU    float64
+	CPUSeconds float64
+}
+
+// maxPhaseThreads is how many top threads each phase lists.
+const maxPhaseThreads = 5
+
+// DetectPhases splits the capture into phases such as idle, ramp-up, steady
+// load, incident and recovery by binary segmentation over SystemMetrics. Each
+// metric is scaled by its noise, estimated from the spread of its snapshot to
+// snapshot changes, and a split is kept while it explains more than Penalty ×
+// metrics × ln(snapshots) of the scaled variance. A capture without change
+// points is a single phase.
+func DetectPhases(data parser.ReportData, cfg PhaseConfig) []Phase {
+	cfg = cfg.withDefaults()
+	n := len(data.Snapshots)
+	if n == 0 {
+		return nil
+	}
+
+	// prefix sums of each scaled metric and its square
+	var sums, squares [][]float64
+	for _, m := range SystemMetrics {
+		values := m.Values(data)
+		sigma := noiseSigma(values)
+		if sigma == 0 {
+			continue
+		}
+		s := make([]float64, n+1)
+		q := make([]float64, n+1)
+		for i, v := range values {
+			z := v / sigma
+			s[i+1] = s[i] + z
+			q[i+1] = q[i] + z*z
+		}
+		sums = append(sums, s)
+		squares = append(squares, q)
+	}
+	// cost is the scaled squared deviation from the mean of snapshots a..b-1
+	cost := func(a, b int) float64 {
+		var c float64
+		for j := range sums {
+			s := sums[j][b] - sums[j][a]
+			c += squares[j][b] - squares[j][a] - s*s/float64(b-a)
+		}
+		return c
+	}
+	penalty := cfg.Penalty * float64(len(sums)) * math.Log(float64(n))
+
+	bounds := []int{0, n}
+	for len(bounds)-1 < cfg.MaxPhases {
+		bestGain, bestSplit := 0.0, -1
+		for i := 0; i+1 < len(bounds); i++ {
+			a, b := bounds[i], bounds[i+1]
+			whole := cost(a, b)
+			for k := a + cfg.MinSnapshots; k <= b-cfg.MinSnapshots; k++ {
+				if gain := whole - cost(a, k) - cost(k, b); gain > bestGain {
+					bestGain, bestSplit = gain, k
+				}
+			}
+		}
+		if bestSplit == -1 || bestGain <= penalty {
+			break
+		}
+		bounds = append(bounds, bestSplit)
+		sort.Ints(bounds)
+	}
+
+	offsets := data.Offsets()
+	intervals := Intervals(data)
+	var phases []Phase
+	for i := 0; i+1 < len(bounds); i++ {
+		a, b := bounds[i], bounds[i+1]
+		segment := parser.ReportData{Snapshots: data.Snapshots[a:b]}
+		p := Phase{Start: a, End: b - 1, Duration: offsets[b-1] - offsets[a] + intervals[b-1]}
+		for _, m := range SystemMetrics {
+			p.Means = append(p.Means, Mean(m.Values(segment)))
+		}
+		stats := ComputeThreadStats(segment) // busiest first
+		if len(stats) > maxPhaseThreads {
+			stats = stats[:maxPhaseThreads]
+		}
+		for _, st := range stats {
+			p.TopThreads = append(p.TopThreads, PhaseThread{
+				TID:        st.TID,
+				Command:    st.Command,
+				MeanCPU:    st.CPU.Mean * float64(st.Samples) / float64(b-a),
+				CPUSeconds: st.CPUSeconds,
+			})
+		}
+		phases = append(phases, p)
+	}
+	return phases
+}
+
+// noiseSigma estimates the noise of a series from the median absolute
+// snapshot to snapshot change, which level shifts barely affect. It is
+// floored at a tenth of the standard deviation so perfectly flat stretches
+// do not make every step look significant, and is 0 for constant series.
+func noiseSigma(values []float64) float64 {
+	std := StdDev(values)
+	if std == 0 || len(values) < 2 {
+		return 0
+	}
+	diffs := make([]float64, len(values)-1)
+	for i := range diffs {
+		diffs[i] = math.Abs(values[i+1] - values[i])
+	}
+	sort.Float64s(diffs)
+	// for Gaussian noise the difference of two samples has √2 times its spread
+	sigma := Percentile(diffs, 50) / 0.6745 / math.Sqrt2
+	return math.Max(sigma, std/10)
+}
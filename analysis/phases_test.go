@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// phasedCapture has a snapshot every 10s: 20 idle, 20 under load with a
+// busy worker, then 20 recovering. Small alternating noise keeps it realistic.
+func phasedCapture() parser.ReportData {
+	return capture(every(60, 10), func(i int, s *parser.Snapshot) {
+		noise := float64(i%3) - 1
+		cpu, load, worker := 5.0, 0.5, 0.0
+		switch {
+		case i >= 40:
+			cpu, load, worker = 20, 2, 10
+		case i >= 20:
+			cpu, load, worker = 60, 8, 90
+		}
+		s.Processes = []parser.ProcessData{
+			{PID: 1, Command: "worker-1", CPU: worker},
+			{PID: 2, Command: "housekeeping", CPU: 1},
+		}
+		s.Metadata.CPUUser = cpu + noise
+		s.Metadata.LoadAvg1 = load + noise/10
+	})
+}
+
+func TestDetectPhases(t *testing.T) {
+	phases := DetectPhases(phasedCapture(), PhaseConfig{})
+	if len(phases) != 3 {
+		t.Fatalf("expected 3 phases, got %d: %+v", len(phases), phases)
+	}
+	for i, want := range [][2]int{{0, 19}, {20, 39}, {40, 59}} {
+		if phases[i].Start != want[0] || phases[i].End != want[1] || phases[i].Duration != 200*time.Second {
+			t.Errorf("phase %d: expected snapshots %v over 200s, got %d-%d over %s", i, want, phases[i].Start, phases[i].End, phases[i].Duration)
+		}
+	}
+	// Means follow SystemMetrics, which starts with total CPU
+	if m := phases[1].Means[0]; m < 59 || m > 61 {
+		t.Errorf("expected the load phase to average 60%% CPU, got %v", m)
+	}
+	if top := phases[1].TopThreads; len(top) != 2 || top[0].Command != "worker-1" {
+		t.Errorf("expected worker-1 to top the load phase, got %+v", top)
+	}
+	if top := phases[0].TopThreads; top[0].Command != "housekeeping" {
+		t.Errorf("expected housekeeping to top the idle phase, got %+v", top)
+	}
+}
+
+func TestDetectPhases_ThreadMeanOverPhase(t *testing.T) {
+	data := phasedCapture()
+	data.Snapshots = data.Snapshots[:20]
+	for i := 1; i < 20; i += 2 {
+		// housekeeping is listed in every other snapshot only
+		data.Snapshots[i].Processes = data.Snapshots[i].Processes[:1]
+	}
+	top := DetectPhases(data, PhaseConfig{})[0].TopThreads
+	if len(top) != 2 || top[0].Command != "housekeeping" || top[0].MeanCPU != 0.5 {
+		t.Errorf("expected housekeeping to average 0.5%% over the phase, got %+v", top)
+	}
+}
+
+func TestDetectPhases_Steady(t *testing.T) {
+	data := phasedCapture()
+	data.Snapshots = data.Snapshots[:20]
+	if phases := DetectPhases(data, PhaseConfig{}); len(phases) != 1 || phases[0].Start != 0 || phases[0].End != 19 {
+		t.Errorf("expected a single phase, got %+v", phases)
+	}
+	if phases := DetectPhases(parser.ReportData{}, PhaseConfig{}); phases != nil {
+		t.Errorf("expected no phases for an empty capture, got %+v", phases)
+	}
+}
+
+func TestDetectPhases_MaxPhases(t *testing.T) {
+	if phases := DetectPhases(phasedCapture(), PhaseConfig{MaxPhases: 2}); len(phases) != 2 {
+		t.Errorf("expected the phase limit to apply, got %d phases", len(phases))
+	}
+}
@@ -0,0 +1,216 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+var (
+	// uuidFragmentRegex matches whole or truncated UUIDs such as "1927b3c3-f"
+	// or "1927b3c3-3473-d", which top cuts off at 15 characters.
+	uuidFragmentRegex = regexp.MustCompile(`[0-9a-fA-F]{8}(-[0-9a-fA-F]{0,12})+`)
+	// hexFragmentRegex matches 0x-prefixed or long standalone hex words that
+	// contain at least one digit, e.g. object addresses.
+	hexFragmentRegex = regexp.MustCompile(`\b(0x[0-9a-fA-F]+|[0-9a-fA-F]*[0-9][0-9a-fA-F]*)\b`)
+	// numericSuffixRegex matches a trailing counter such as "command5" or "Thread#3".
+	numericSuffixRegex = regexp.MustCompile(`[-_#.\s]*\d+$`)
+)
+
+// PoolRule maps thread names matching Pattern to the pool named Pool, which
+// may reference capture groups such as $1.
+type PoolRule struct {
+	Pattern string `json:"pattern"`
+	Pool    string `json:"pool"`
+}
+
+// PoolConfig configures thread name normalization. The built-in steps are on
+// unless explicitly disabled.
+type PoolConfig struct {
+	StripNumericSuffix *bool      `json:"stripNumericSuffix,omitempty"`
+	StripUUID          *bool      `json:"stripUUID,omitempty"`
+	StripHex           *bool      `json:"stripHex,omitempty"`
+	Rules              []PoolRule `json:"rules,omitempty"`
+}
+
+// ReadPoolConfig decodes a JSON pool configuration.
+func ReadPoolConfig(r io.Reader) (PoolConfig, error) {
+	var cfg PoolConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return PoolConfig{}, fmt.Errorf("decode pool config: %w", err)
+	}
+	return cfg, nil
+}
+
+type compiledPoolRule struct {
+	re   *regexp.Regexp
+	pool string
+}
+
+// Normalizer maps thread names to pool names.
+type Normalizer struct {
+	stripNumericSuffix bool
+	stripUUID          bool
+	stripHex           bool
+	rules              []compiledPoolRule
+}
+
+// DefaultNormalizer returns a Normalizer with every built-in step enabled and
+// no user rules.
+func DefaultNormalizer() *Normalizer {
+	n, _ := NewNormalizer(PoolConfig{})
+	return n
+}
+
+// NewNormalizer builds a Normalizer from cfg, failing on invalid rule patterns.
+func NewNormalizer(cfg PoolConfig) (*Normalizer, error) {
+	enabled := func(b *bool) bool { return b == nil || *b }
+	n := &Normalizer{
+		stripNumericSuffix: enabled(cfg.StripNumericSuffix),
+		stripUUID:          enabled(cfg.StripUUID),
+		stripHex:           enabled(cfg.StripHex),
+	}
+	for _, r := range cfg.Rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool rule %q: %w", r.Pattern, err)
+		}
+		n.rules = append(n.rules, compiledPoolRule{re: re, pool: r.Pool})
+	}
+	return n, nil
+}
+
+// Normalize returns the pool name for a thread name. The first matching user
+// rule wins; otherwise the enabled built-in steps are applied in turn.
+func (n *Normalizer) Normalize(name string) string {
+	for _, r := range n.rules {
+		if m := r.re.FindStringSubmatchIndex(name); m != nil {
+			return string(r.re.ExpandString(nil, r.pool, name, m))
+		}
+	}
+
+	pool := name
+	if n.stripUUID {
+		pool = uuidFragmentRegex.ReplaceAllString(pool, "<uuid>")
+	}
+	if n.stripHex {
+		pool = hexFragmentRegex.ReplaceAllStringFunc(pool, func(word string) string {
+			// short words like "e0" or "C2" are names, not addresses
+			if strings.HasPrefix(word, "0x") || len(word) >= 6 {
+				return "<hex>"
+			}
+			return word
+		})
+	}
+	if n.stripNumericSuffix {
+		pool = stripNumericSuffix(pool)
+	}
+	pool = strings.TrimSpace(pool)
+	if pool == "" {
+		return name
+	}
+	return pool
+}
+
+// stripNumericSuffix drops a trailing counter from the last word of a masked
+// thread name. A word that is just a letter and a number, such as "e0" or
+// "C2", names the thread rather than counts it and is kept whole, so "e0" and
+// the longer "e0 - <uuid>" keep the same prefix.
+func stripNumericSuffix(name string) string {
+	loc := numericSuffixRegex.FindStringIndex(name)
+	if loc == nil {
+		return name
+	}
+	stem := name[:loc[0]]
+	if word := stem[strings.LastIndexAny(stem, " \t")+1:]; len(word) < 2 {
+		return name
+	}
+	return stem
+}
+
+// PoolSeries is the combined CPU of the threads sharing a pool name.
+type PoolSeries struct {
+	Name    string
+	TIDs    []int
+	CPU     []float64 // sum of member %CPU per snapshot
+	Threads []int     // members listed per snapshot
+}
+
+// Pools groups threads by normalized name, ordered by total CPU, busiest first.
+func Pools(data parser.ReportData, n *Normalizer) []PoolSeries {
+	if n == nil {
+		n = DefaultNormalizer()
+	}
+	index := make(map[string]int)
+	var pools []PoolSeries
+	for _, t := range Threads(data) {
+		name := n.Normalize(t.Command)
+		idx, ok := index[name]
+		if !ok {
+			idx = len(pools)
+			index[name] = idx
+			pools = append(pools, PoolSeries{
+				Name:    name,
+				CPU:     make([]float64, len(data.Snapshots)),
+				Threads: make([]int, len(data.Snapshots)),
+			})
+		}
+		p := &pools[idx]
+		p.TIDs = append(p.TIDs, t.TID)
+		for i, present := range t.Present {
+			if present {
+				p.CPU[i] += t.CPU[i]
+				p.Threads[i]++
+			}
+		}
+	}
+	sort.SliceStable(pools, func(i, j int) bool {
+		return sum(pools[i].CPU) > sum(pools[j].CPU)
+	})
+	return pools
+}
+
+// PoolStats summarizes the CPU usage of one pool.
+type PoolStats struct {
+	Name       string
+	Threads    int     // distinct TIDs seen in the pool
+	CPU        Summary // distribution of the pool's summed %CPU per snapshot
+	CPUSeconds float64
+}
+
+// ComputePoolStats returns per-pool statistics ordered by CPU-seconds.
+func ComputePoolStats(data parser.ReportData, n *Normalizer) []PoolStats {
+	intervals := Intervals(data)
+	var stats []PoolStats
+	for _, p := range Pools(data, n) {
+		var cpuSeconds float64
+		for i, v := range p.CPU {
+			cpuSeconds += v / 100 * intervals[i].Seconds()
+		}
+		stats = append(stats, PoolStats{
+			Name:       p.Name,
+			Threads:    len(p.TIDs),
+			CPU:        Summarize(p.CPU),
+			CPUSeconds: cpuSeconds,
+		})
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].CPUSeconds > stats[j].CPUSeconds
+	})
+	return stats
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
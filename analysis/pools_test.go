@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestNormalize_BuiltIns(t *testing.T) {
+	n := DefaultNormalizer()
+	for in, want := range map[string]string{
+		"rbound-command1": "rbound-command",
+		"rbound-command5": "rbound-command",
+		"foreman15":       "foreman",
+		"GC Thread#3":     "GC Thread",
+		"C2 CompilerThre": "C2 CompilerThre",
+		"e0 - 1927b3c3-f": "e0 - <uuid>",
+		"e0":              "e0",
+		"e12":             "e12",
+		"worker 7":        "worker",
+		"1927b3c3-3473-d": "<uuid>",
+		"lock 0x7f3a2c":   "lock <hex>",
+		"42":              "42",
+	} {
+		if got := n.Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalize_UserRulesAndToggles(t *testing.T) {
+	off := false
+	cfg, err := ReadPoolConfig(strings.NewReader(`{"stripNumericSuffix": false, "rules": [{"pattern": "^(C\\d) Compiler", "pool": "JIT $1"}]}`))
+	if err != nil {
+		t.Fatalf("ReadPoolConfig failed: %v", err)
+	}
+	if cfg.StripNumericSuffix == nil || *cfg.StripNumericSuffix != off {
+		t.Fatalf("stripNumericSuffix not decoded: %+v", cfg)
+	}
+	n, err := NewNormalizer(cfg)
+	if err != nil {
+		t.Fatalf("NewNormalizer failed: %v", err)
+	}
+	if got := n.Normalize("C2 CompilerThre"); got != "JIT C2" {
+		t.Errorf("rule not applied, got %q", got)
+	}
+	if got := n.Normalize("foreman15"); got != "foreman15" {
+		t.Errorf("numeric suffix stripped although disabled, got %q", got)
+	}
+}
+
+func TestNewNormalizer_InvalidRule(t *testing.T) {
+	if _, err := NewNormalizer(PoolConfig{Rules: []PoolRule{{Pattern: "("}}}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestPools(t *testing.T) {
+	data := capture(every(2, 2), listed(
+		[]parser.ProcessData{
+			{PID: 1, Command: "rbound-command1", CPU: 10},
+			{PID: 2, Command: "rbound-command2", CPU: 20},
+			{PID: 3, Command: "other", CPU: 5},
+		},
+		[]parser.ProcessData{{PID: 2, Command: "rbound-command2", CPU: 40}},
+	))
+	pools := Pools(data, nil)
+	if len(pools) != 2 {
+		t.Fatalf("got %d pools, want 2", len(pools))
+	}
+	p := pools[0]
+	if p.Name != "rbound-command" || len(p.TIDs) != 2 {
+		t.Errorf("unexpected first pool: %+v", p)
+	}
+	if p.CPU[0] != 30 || p.CPU[1] != 40 || p.Threads[0] != 2 || p.Threads[1] != 1 {
+		t.Errorf("unexpected pool series: %+v", p)
+	}
+
+	stats := ComputePoolStats(data, nil)
+	if stats[0].Name != "rbound-command" || stats[0].Threads != 2 || stats[0].CPU.Max != 40 {
+		t.Errorf("unexpected pool stats: %+v", stats[0])
+	}
+}
@@ -0,0 +1,94 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// SamplingGap is a stretch between two snapshots that is noticeably longer
+// than the nominal interval, usually iterations top missed on a busy host.
+type SamplingGap struct {
+	After    int // index of the snapshot before the gap
+	Interval time.Duration
+	Missing  int // snapshots expected in the gap at the nominal interval
+}
+
+// Sampling describes how regularly the snapshots were taken.
+type Sampling struct {
+	Nominal time.Duration // inferred -d interval
+	// Jitter is the mean absolute deviation from Nominal of the intervals
+	// that are neither gaps nor duplicates.
+	Jitter time.Duration
+	// Irregular counts intervals off by at least a second or a quarter of
+	// Nominal that are not gaps.
+	Irregular  int
+	Gaps       []SamplingGap
+	Duplicates []int // indices of snapshots with the same timestamp as the one before
+	Missing    int   // total snapshots missing across all gaps
+}
+
+// gapFactor is how many nominal intervals an interval must span to be a gap.
+const gapFactor = 1.5
+
+// AnalyzeSampling infers the nominal interval as the median time between
+// snapshots and flags gaps, jitter and duplicate timestamps. top prints whole
+// seconds, so an interval must exceed the nominal one by more than a second
+// to count as a gap.
+func AnalyzeSampling(data parser.ReportData) Sampling {
+	var s Sampling
+	offsets := data.Offsets()
+	var positive []float64
+	for i := 1; i < len(offsets); i++ {
+		if d := offsets[i] - offsets[i-1]; d > 0 {
+			positive = append(positive, d.Seconds())
+		} else {
+			s.Duplicates = append(s.Duplicates, i)
+		}
+	}
+	if len(positive) == 0 {
+		return s
+	}
+	sort.Float64s(positive)
+	s.Nominal = time.Duration(math.Round(Percentile(positive, 50))) * time.Second
+	if s.Nominal <= 0 {
+		s.Nominal = time.Second
+	}
+
+	tolerance := s.Nominal / 4
+	if tolerance < time.Second {
+		tolerance = time.Second
+	}
+	var deviation time.Duration
+	regular := 0
+	for i := 1; i < len(offsets); i++ {
+		d := offsets[i] - offsets[i-1]
+		if d <= 0 {
+			continue
+		}
+		if float64(d) >= gapFactor*float64(s.Nominal) && d-s.Nominal > tolerance {
+			missing := int(math.Round(float64(d)/float64(s.Nominal))) - 1
+			if missing < 1 {
+				missing = 1
+			}
+			s.Gaps = append(s.Gaps, SamplingGap{After: i - 1, Interval: d, Missing: missing})
+			s.Missing += missing
+			continue
+		}
+		off := d - s.Nominal
+		if off < 0 {
+			off = -off
+		}
+		if off >= tolerance {
+			s.Irregular++
+		}
+		deviation += off
+		regular++
+	}
+	if regular > 0 {
+		s.Jitter = deviation / time.Duration(regular)
+	}
+	return s
+}
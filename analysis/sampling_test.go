@@ -0,0 +1,40 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyzeSampling(t *testing.T) {
+	// -d 2 with one 3s hiccup, a 10s gap and a duplicate timestamp
+	s := AnalyzeSampling(capture([]int{0, 2, 4, 7, 9, 19, 21, 21, 23}, nil))
+	if s.Nominal != 2*time.Second {
+		t.Errorf("expected a 2s nominal interval, got %v", s.Nominal)
+	}
+	if len(s.Gaps) != 1 || s.Gaps[0].After != 4 || s.Gaps[0].Interval != 10*time.Second || s.Gaps[0].Missing != 4 {
+		t.Errorf("unexpected gaps: %+v", s.Gaps)
+	}
+	if s.Missing != 4 {
+		t.Errorf("expected 4 missing snapshots, got %d", s.Missing)
+	}
+	if len(s.Duplicates) != 1 || s.Duplicates[0] != 7 {
+		t.Errorf("unexpected duplicates: %v", s.Duplicates)
+	}
+	if s.Irregular != 1 {
+		t.Errorf("expected one irregular interval, got %d", s.Irregular)
+	}
+	// six regular intervals, one of them 1s off
+	if want := time.Second / 6; s.Jitter != want {
+		t.Errorf("expected jitter %v, got %v", want, s.Jitter)
+	}
+}
+
+func TestAnalyzeSampling_Regular(t *testing.T) {
+	s := AnalyzeSampling(capture(every(4, 5), nil))
+	if s.Nominal != 5*time.Second || len(s.Gaps) != 0 || s.Irregular != 0 || s.Jitter != 0 {
+		t.Errorf("expected a perfectly regular capture, got %+v", s)
+	}
+	if s := AnalyzeSampling(capture([]int{0}, nil)); s.Nominal != 0 {
+		t.Errorf("expected no interval for a single snapshot, got %+v", s)
+	}
+}
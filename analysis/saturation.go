@@ -0,0 +1,205 @@
+package analysis
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// Where the core count used for saturation analysis came from
+const (
+	CoresFromFlag     = "flag"
+	CoresFromPerCore  = "per-core CPU lines"
+	CoresFromMetadata = "metadata"
+)
+
+// Saturation verdicts
+const (
+	VerdictNone         = ""
+	VerdictSingleThread = "saturated by a single thread"
+	VerdictHost         = "host CPU saturated"
+)
+
+// CoreCount returns the host's core count and where it came from: the
+// override when positive, otherwise the per-core lines in the capture,
+// otherwise a "cores", "cpus" or "nproc" field in the JSON metadata. It
+// returns 0 when none of them say.
+func CoreCount(data parser.ReportData, override int, metadata string) (int, string) {
+	if override > 0 {
+		return override, CoresFromFlag
+	}
+	cores := 0
+	for _, s := range data.Snapshots {
+		if s.Metadata.CPUCores > cores {
+			cores = s.Metadata.CPUCores
+		}
+	}
+	if cores > 0 {
+		return cores, CoresFromPerCore
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal([]byte(metadata), &fields) != nil {
+		return 0, ""
+	}
+	for _, key := range []string{"cores", "cpus", "nproc"} {
+		switch v := fields[key].(type) {
+		case float64:
+			if v > 0 {
+				return int(v), CoresFromMetadata
+			}
+		case string:
+			if n, err := strconv.Atoi(v); err == nil && n > 0 {
+				return n, CoresFromMetadata
+			}
+		}
+	}
+	return 0, ""
+}
+
+// SaturationConfig tunes saturation detection. Zero fields fall back to
+// defaults.
+type SaturationConfig struct {
+	// Cores is the host's core count; 0 when unknown.
+	Cores int
+	// PeggedCPU is the %CPU a thread must stay at or above, default 95.
+	PeggedCPU float64
+	// HostBusy is the host CPU % counted as saturated, default 90.
+	HostBusy float64
+	// MinDuration is how long either condition must last, default 30s.
+	MinDuration time.Duration
+}
+
+func (c SaturationConfig) withDefaults() SaturationConfig {
+	if c.PeggedCPU <= 0 {
+		c.PeggedCPU = 95
+	}
+	if c.HostBusy <= 0 {
+		c.HostBusy = 90
+	}
+	if c.MinDuration <= 0 {
+		c.MinDuration = 30 * time.Second
+	}
+	return c
+}
+
+// SaturationPeriod is a sustained run of snapshots in which a thread was
+// pegged (TID set) or the whole host was busy.
+type SaturationPeriod struct {
+	TID      int
+	Command  string
+	Start    int
+	End      int
+	Duration time.Duration
+	MeanCPU  float64 // mean %CPU of the thread, or the saturation measure for host periods
+	HostBusy float64 // mean host busy % over the period
+}
+
+// Saturation is the result of DetectSaturation.
+type Saturation struct {
+	Cores    int
+	Verdict  string
+	Pegged   []SaturationPeriod // threads pegged while the host had spare capacity
+	Host     []SaturationPeriod // periods where the host itself was saturated
+	HostBusy []float64          // host busy % per snapshot
+	TopCPU   []float64          // highest single-thread %CPU per snapshot
+}
+
+// HostBusy returns how busy the host's CPUs were in a snapshot, in percent of
+// all cores. It uses user plus system time from the "%Cpu(s)" summary when
+// present, so iowait and steal do not count as busy, and otherwise spreads the
+// listed threads' CPU over cores, returning 0 if that is unknown too.
+func HostBusy(s parser.Snapshot, cores int) float64 {
+	m := s.Metadata
+	if m.CPUUser+m.CPUSystem+m.CPUIdle+m.CPUWait+m.CPUSteal > 0 {
+		return m.CPUUser + m.CPUSystem
+	}
+	return threadBusy(s, cores)
+}
+
+// threadBusy is the listed threads' summed %CPU against cores × 100%, or 0
+// when the core count is unknown.
+func threadBusy(s parser.Snapshot, cores int) float64 {
+	if cores <= 0 {
+		return 0
+	}
+	var total float64
+	for _, p := range s.Processes {
+		total += p.CPU
+	}
+	return total / float64(cores)
+}
+
+// DetectSaturation finds threads that stay pegged near 100% of a core and
+// periods where the whole host is busy, and decides which one explains the
+// capture: a pegged thread while the host was mostly idle is a single-thread
+// bottleneck, a sustained busy host is host saturation.
+func DetectSaturation(data parser.ReportData, cfg SaturationConfig) Saturation {
+	cfg = cfg.withDefaults()
+	n := len(data.Snapshots)
+	result := Saturation{
+		Cores:    cfg.Cores,
+		HostBusy: make([]float64, n),
+		TopCPU:   make([]float64, n),
+	}
+	// Saturation is judged on the listed threads against the cores when their
+	// number is known and on the host's busy % otherwise
+	load := make([]float64, n)
+	for i, s := range data.Snapshots {
+		result.HostBusy[i] = HostBusy(s, cfg.Cores)
+		load[i] = result.HostBusy[i]
+		if cfg.Cores > 0 {
+			load[i] = threadBusy(s, cfg.Cores)
+		}
+		for _, p := range s.Processes {
+			if p.CPU > result.TopCPU[i] {
+				result.TopCPU[i] = p.CPU
+			}
+		}
+	}
+
+	intervals := Intervals(data)
+	offsets := data.Offsets()
+	sustained := func(flagged func(i int) bool) [][2]int {
+		return sustainedRuns(data, cfg.MinDuration, flagged)
+	}
+	period := func(r [2]int, values []float64) SaturationPeriod {
+		return SaturationPeriod{
+			Start:    r[0],
+			End:      r[1],
+			Duration: offsets[r[1]] - offsets[r[0]] + intervals[r[1]],
+			MeanCPU:  Mean(values[r[0] : r[1]+1]),
+			HostBusy: Mean(result.HostBusy[r[0] : r[1]+1]),
+		}
+	}
+
+	for _, r := range sustained(func(i int) bool { return load[i] >= cfg.HostBusy }) {
+		result.Host = append(result.Host, period(r, load))
+	}
+	for _, t := range Threads(data) {
+		for _, r := range sustained(func(i int) bool { return t.Present[i] && t.CPU[i] >= cfg.PeggedCPU }) {
+			// a pegged thread on a saturated host is just one of many busy threads
+			if Mean(load[r[0]:r[1]+1]) >= cfg.HostBusy {
+				continue
+			}
+			p := period(r, t.CPU)
+			p.TID = t.TID
+			p.Command = t.Command
+			result.Pegged = append(result.Pegged, p)
+		}
+	}
+
+	sort.SliceStable(result.Pegged, func(i, j int) bool {
+		return result.Pegged[i].Start < result.Pegged[j].Start
+	})
+
+	switch {
+	case len(result.Host) > 0:
+		result.Verdict = VerdictHost
+	case len(result.Pegged) > 0:
+		result.Verdict = VerdictSingleThread
+	}
+	return result
+}
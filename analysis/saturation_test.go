@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestCoreCount(t *testing.T) {
+	perCore := parser.ReportData{Snapshots: []parser.Snapshot{{Metadata: parser.Metadata{CPUCores: 8}}}}
+	tests := []struct {
+		name     string
+		data     parser.ReportData
+		override int
+		metadata string
+		cores    int
+		source   string
+	}{
+		{"flag wins", perCore, 4, `{"cores": 2}`, 4, CoresFromFlag},
+		{"per-core lines", perCore, 0, `{"cores": 2}`, 8, CoresFromPerCore},
+		{"metadata number", parser.ReportData{}, 0, `{"cores": 2}`, 2, CoresFromMetadata},
+		{"metadata string", parser.ReportData{}, 0, `{"nproc": "16"}`, 16, CoresFromMetadata},
+		{"unknown", parser.ReportData{}, 0, "not json", 0, ""},
+	}
+	for _, tt := range tests {
+		cores, source := CoreCount(tt.data, tt.override, tt.metadata)
+		if cores != tt.cores || source != tt.source {
+			t.Errorf("%s: got %d from %q, want %d from %q", tt.name, cores, source, tt.cores, tt.source)
+		}
+	}
+}
+
+// saturationData returns 30 snapshots two seconds apart with a planner thread
+// at plannerCPU from snapshot 5 to 24 and the host at idle % idle.
+func saturationData(plannerCPU, idle float64) parser.ReportData {
+	return capture(every(30, 2), func(i int, s *parser.Snapshot) {
+		cpu := 5.0
+		if i >= 5 && i < 25 {
+			cpu = plannerCPU
+		}
+		s.Processes = []parser.ProcessData{{PID: 42, Command: "planner", CPU: cpu}, {PID: 43, Command: "other", CPU: 1}}
+		s.Metadata = parser.Metadata{CPUUser: 100 - idle, CPUIdle: idle}
+	})
+}
+
+func TestDetectSaturation_SingleThread(t *testing.T) {
+	sat := DetectSaturation(saturationData(99.5, 85), SaturationConfig{Cores: 8})
+	if sat.Verdict != VerdictSingleThread {
+		t.Fatalf("expected a single-thread verdict, got %+v", sat)
+	}
+	if len(sat.Pegged) != 1 {
+		t.Fatalf("expected one pegged period, got %+v", sat.Pegged)
+	}
+	p := sat.Pegged[0]
+	if p.TID != 42 || p.Start != 5 || p.End != 24 || p.Duration != 40*time.Second || p.MeanCPU != 99.5 || p.HostBusy != 15 {
+		t.Errorf("unexpected pegged period: %+v", p)
+	}
+	if sat.TopCPU[10] != 99.5 || sat.HostBusy[10] != 15 {
+		t.Errorf("unexpected evidence series: top %v busy %v", sat.TopCPU[10], sat.HostBusy[10])
+	}
+}
+
+func TestDetectSaturation_ShortSpikeIgnored(t *testing.T) {
+	sat := DetectSaturation(saturationData(99.5, 85), SaturationConfig{MinDuration: time.Minute})
+	if sat.Verdict != VerdictNone || len(sat.Pegged) != 0 {
+		t.Errorf("expected no verdict for a 40s run with a 1m minimum, got %+v", sat)
+	}
+}
+
+func TestDetectSaturation_Host(t *testing.T) {
+	sat := DetectSaturation(saturationData(99.5, 2), SaturationConfig{})
+	if sat.Verdict != VerdictHost || len(sat.Host) != 1 || sat.Host[0].Start != 0 || sat.Host[0].End != 29 {
+		t.Errorf("expected the whole capture to be host saturated, got %+v", sat.Host)
+	}
+	if len(sat.Pegged) != 0 {
+		t.Errorf("pegged threads on a saturated host should not be reported: %+v", sat.Pegged)
+	}
+}
+
+func TestDetectSaturation_IOWaitIsNotBusy(t *testing.T) {
+	data := saturationData(99.5, 5)
+	for i := range data.Snapshots {
+		data.Snapshots[i].Metadata = parser.Metadata{CPUUser: 10, CPUWait: 85, CPUIdle: 5}
+	}
+	sat := DetectSaturation(data, SaturationConfig{})
+	if len(sat.Host) != 0 || sat.HostBusy[10] != 10 {
+		t.Errorf("an I/O stall should not count as host saturation, got %+v busy %v", sat.Host, sat.HostBusy[10])
+	}
+	if sat.Verdict != VerdictSingleThread || len(sat.Pegged) != 1 {
+		t.Errorf("expected the pegged planner to be reported, got %+v", sat)
+	}
+}
+
+func TestDetectSaturation_CoresJudgeThreads(t *testing.T) {
+	// the summary says the host is mostly idle, but one core is all there is
+	sat := DetectSaturation(saturationData(99.5, 85), SaturationConfig{Cores: 1})
+	if sat.Verdict != VerdictHost || len(sat.Host) != 1 || sat.Host[0].Start != 5 || sat.Host[0].End != 24 {
+		t.Errorf("expected the planner to saturate a single core host, got %+v", sat.Host)
+	}
+	if sat.HostBusy[10] != 15 {
+		t.Errorf("expected the busy series to stay user + system, got %v", sat.HostBusy[10])
+	}
+}
+
+func TestHostBusy_FallsBackToThreadCPU(t *testing.T) {
+	s := parser.Snapshot{Processes: []parser.ProcessData{{CPU: 100}, {CPU: 100}}}
+	if got := HostBusy(s, 4); got != 50 {
+		t.Errorf("expected 50%% busy, got %v", got)
+	}
+	if got := HostBusy(s, 0); got != 0 {
+		t.Errorf("expected 0 with unknown cores, got %v", got)
+	}
+	s.Metadata = parser.Metadata{CPUUser: 20, CPUSystem: 5, CPUWait: 60, CPUSteal: 10, CPUIdle: 5}
+	if got := HostBusy(s, 4); got != 25 {
+		t.Errorf("expected user + system only, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// StateRun is a stretch of consecutive snapshots in which a thread was listed
+// with the same state.
+type StateRun struct {
+	State string
+	Start int // first snapshot index
+	End   int // last snapshot index
+}
+
+// StateRuns splits a thread's history into runs of the same state. Snapshots
+// where the thread was not listed end the current run.
+func StateRuns(t ThreadSeries) []StateRun {
+	var runs []StateRun
+	for i, present := range t.Present {
+		if !present {
+			continue
+		}
+		if n := len(runs); n > 0 && runs[n-1].State == t.State[i] && runs[n-1].End == i-1 {
+			runs[n-1].End = i
+			continue
+		}
+		runs = append(runs, StateRun{State: t.State[i], Start: i, End: i})
+	}
+	return runs
+}
+
+// BlockedPeriod is a run of uninterruptible sleep (state D), usually a thread
+// waiting on disk or network storage.
+type BlockedPeriod struct {
+	TID     int
+	Command string
+	Start   int
+	End     int
+	IOWait  float64 // mean system iowait % over the period
+}
+
+// BlockedPeriods returns every D run in the capture ordered by start, with
+// the iowait it coincided with.
+func BlockedPeriods(data parser.ReportData) []BlockedPeriod {
+	var periods []BlockedPeriod
+	for _, t := range Threads(data) {
+		for _, r := range StateRuns(t) {
+			if r.State != "D" {
+				continue
+			}
+			var wait float64
+			for i := r.Start; i <= r.End; i++ {
+				wait += data.Snapshots[i].Metadata.CPUWait
+			}
+			periods = append(periods, BlockedPeriod{
+				TID:     t.TID,
+				Command: t.Command,
+				Start:   r.Start,
+				End:     r.End,
+				IOWait:  wait / float64(r.End-r.Start+1),
+			})
+		}
+	}
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].Start < periods[j].Start
+	})
+	return periods
+}
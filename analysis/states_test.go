@@ -0,0 +1,42 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestStateRuns(t *testing.T) {
+	ts := ThreadSeries{
+		State:   []string{"S", "S", "D", "", "D", "R"},
+		Present: []bool{true, true, true, false, true, true},
+	}
+	want := []StateRun{{"S", 0, 1}, {"D", 2, 2}, {"D", 4, 4}, {"R", 5, 5}}
+	runs := StateRuns(ts)
+	if len(runs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, runs)
+	}
+	for i := range want {
+		if runs[i] != want[i] {
+			t.Errorf("run %d: expected %v, got %v", i, want[i], runs[i])
+		}
+	}
+}
+
+func TestBlockedPeriods(t *testing.T) {
+	states := []string{"S", "D", "D", "S"}
+	periods := BlockedPeriods(capture(every(len(states), 2), func(i int, s *parser.Snapshot) {
+		s.Processes = []parser.ProcessData{
+			{PID: 1, Command: "main", S: "S"},
+			{PID: 2, Command: "io", S: states[i]},
+		}
+		s.Metadata.CPUWait = float64(i * 10)
+	}))
+	if len(periods) != 1 {
+		t.Fatalf("expected one blocked period, got %+v", periods)
+	}
+	p := periods[0]
+	if p.TID != 2 || p.Start != 1 || p.End != 2 || p.IOWait != 15 {
+		t.Errorf("unexpected blocked period: %+v", p)
+	}
+}
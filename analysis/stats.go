@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary describes the distribution of a series of values.
+type Summary struct {
+	Min    float64
+	Mean   float64
+	Median float64
+	P95    float64
+	P99    float64
+	Max    float64
+}
+
+// Summarize computes the distribution of values. An empty input yields the
+// zero Summary.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return Summary{
+		Min:    sorted[0],
+		Mean:   Mean(values),
+		Median: Percentile(sorted, 50),
+		P95:    Percentile(sorted, 95),
+		P99:    Percentile(sorted, 99),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// Percentile returns the p-th percentile (0-100) of sorted values using
+// linear interpolation between the closest ranks.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if hi >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	frac := rank - float64(lo)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*frac
+}
+
+// Mean returns the arithmetic mean of values, or 0 when there are none.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StdDev returns the population standard deviation of values.
+func StdDev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	mean := Mean(values)
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sq / float64(len(values)))
+}
+
+// LinearFit fits y = slope*x + intercept by least squares and returns the
+// coefficient of determination r2. Fewer than two points, or x values that
+// are all equal, yield a zero fit.
+func LinearFit(x, y []float64) (slope, intercept, r2 float64) {
+	n := len(x)
+	if n < 2 || len(y) != n {
+		return 0, 0, 0
+	}
+	mx, my := Mean(x), Mean(y)
+	var sxx, sxy, syy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, my, 0
+	}
+	slope = sxy / sxx
+	intercept = my - slope*mx
+	if syy == 0 {
+		return slope, intercept, 1
+	}
+	return slope, intercept, sxy * sxy / (sxx * syy)
+}
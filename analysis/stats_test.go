@@ -0,0 +1,54 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	var values []float64
+	for i := 100; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+	got := Summarize(values)
+	want := Summary{Min: 1, Mean: 50.5, Median: 50.5, P95: 95.05, P99: 99.01, Max: 100}
+	for name, pair := range map[string][2]float64{
+		"min": {got.Min, want.Min}, "mean": {got.Mean, want.Mean}, "median": {got.Median, want.Median},
+		"p95": {got.P95, want.P95}, "p99": {got.P99, want.P99}, "max": {got.Max, want.Max},
+	} {
+		if math.Abs(pair[0]-pair[1]) > 1e-9 {
+			t.Errorf("%s = %v, want %v", name, pair[0], pair[1])
+		}
+	}
+	if values[0] != 100 {
+		t.Error("Summarize must not reorder its input")
+	}
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	if got := Summarize(nil); got != (Summary{}) {
+		t.Errorf("Summarize(nil) = %+v, want zero", got)
+	}
+}
+
+func TestPercentile_SingleValue(t *testing.T) {
+	if got := Percentile([]float64{42}, 99); got != 42 {
+		t.Errorf("Percentile = %v, want 42", got)
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	if got := StdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9}); got != 2 {
+		t.Errorf("StdDev = %v, want 2", got)
+	}
+}
+
+func TestLinearFit(t *testing.T) {
+	slope, intercept, r2 := LinearFit([]float64{0, 1, 2, 3}, []float64{1, 3, 5, 7})
+	if slope != 2 || intercept != 1 || r2 != 1 {
+		t.Errorf("LinearFit = %v, %v, %v; want 2, 1, 1", slope, intercept, r2)
+	}
+	if s, _, _ := LinearFit([]float64{1}, []float64{1}); s != 0 {
+		t.Error("expected zero fit for a single point")
+	}
+}
@@ -0,0 +1,149 @@
+package analysis
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// ThreadSeries is the history of one thread (TID) across the capture. CPU is
+// 0 and State is empty for snapshots where the thread was not listed.
+type ThreadSeries struct {
+	TID     int
+	Command string
+	User    string
+	CPU     []float64
+	State   []string
+	Present []bool
+}
+
+// Samples returns the number of snapshots in which the thread was listed.
+func (t ThreadSeries) Samples() int {
+	n := 0
+	for _, p := range t.Present {
+		if p {
+			n++
+		}
+	}
+	return n
+}
+
+// Threads returns the series of every thread in order of first appearance.
+func Threads(data parser.ReportData) []ThreadSeries {
+	index := make(map[int]int)
+	var threads []ThreadSeries
+	n := len(data.Snapshots)
+	for i, s := range data.Snapshots {
+		for _, p := range s.Processes {
+			idx, ok := index[p.PID]
+			if !ok {
+				idx = len(threads)
+				index[p.PID] = idx
+				threads = append(threads, ThreadSeries{
+					TID:     p.PID,
+					Command: p.Command,
+					User:    p.User,
+					CPU:     make([]float64, n),
+					State:   make([]string, n),
+					Present: make([]bool, n),
+				})
+			}
+			threads[idx].CPU[i] = p.CPU
+			threads[idx].State[i] = p.S
+			threads[idx].Present[i] = true
+		}
+	}
+	return threads
+}
+
+// Intervals returns how long each snapshot represents: the time until the
+// next snapshot, with the last one lasting as long as the one before it.
+func Intervals(data parser.ReportData) []time.Duration {
+	offsets := data.Offsets()
+	intervals := make([]time.Duration, len(offsets))
+	for i := range offsets {
+		switch {
+		case i+1 < len(offsets):
+			intervals[i] = offsets[i+1] - offsets[i]
+		case i > 0:
+			intervals[i] = intervals[i-1]
+		}
+	}
+	return intervals
+}
+
+// sustainedRuns returns the runs of consecutive snapshots for which flagged is
+// true that last at least minDuration, as inclusive [start, end] indices.
+func sustainedRuns(data parser.ReportData, minDuration time.Duration, flagged func(i int) bool) [][2]int {
+	offsets := data.Offsets()
+	intervals := Intervals(data)
+	n := len(offsets)
+	var runs [][2]int
+	start := -1
+	for i := 0; i <= n; i++ {
+		if i < n && flagged(i) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			if offsets[i-1]-offsets[start]+intervals[i-1] >= minDuration {
+				runs = append(runs, [2]int{start, i - 1})
+			}
+			start = -1
+		}
+	}
+	return runs
+}
+
+// ThreadStats summarizes the CPU usage of one thread.
+type ThreadStats struct {
+	TID     int
+	Command string
+	User    string
+	Samples int     // snapshots in which the thread was listed
+	CPU     Summary // distribution of %CPU over the samples seen
+	// CPUSeconds estimates the CPU time consumed, integrating %CPU over each
+	// snapshot's interval.
+	CPUSeconds float64
+	// RunningFraction is the share of the capture's snapshots in which the
+	// thread was in state R.
+	RunningFraction float64
+}
+
+// ComputeThreadStats returns per-thread statistics ordered by CPU-seconds,
+// busiest first.
+func ComputeThreadStats(data parser.ReportData) []ThreadStats {
+	intervals := Intervals(data)
+	var stats []ThreadStats
+	for _, t := range Threads(data) {
+		var seen []float64
+		var cpuSeconds float64
+		running := 0
+		for i, present := range t.Present {
+			if !present {
+				continue
+			}
+			seen = append(seen, t.CPU[i])
+			cpuSeconds += t.CPU[i] / 100 * intervals[i].Seconds()
+			if t.State[i] == "R" {
+				running++
+			}
+		}
+		stats = append(stats, ThreadStats{
+			TID:             t.TID,
+			Command:         t.Command,
+			User:            t.User,
+			Samples:         len(seen),
+			CPU:             Summarize(seen),
+			CPUSeconds:      cpuSeconds,
+			RunningFraction: float64(running) / float64(len(data.Snapshots)),
+		})
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].CPUSeconds > stats[j].CPUSeconds
+	})
+	return stats
+}
@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func snapshotAt(sec int, procs ...parser.ProcessData) parser.Snapshot {
+	return parser.Snapshot{Time: time.Date(0, 1, 1, 12, 0, sec, 0, time.UTC), Processes: procs}
+}
+
+// capture builds a test capture with a snapshot at each of seconds past
+// 12:00:00, handing each one to shape, when not nil, to fill in its threads
+// and metadata. Every fixture in this package is built with it.
+func capture(seconds []int, shape func(i int, s *parser.Snapshot)) parser.ReportData {
+	var data parser.ReportData
+	for i, sec := range seconds {
+		s := snapshotAt(sec)
+		if shape != nil {
+			shape(i, &s)
+		}
+		data.Snapshots = append(data.Snapshots, s)
+	}
+	return data
+}
+
+// every returns n snapshot times step seconds apart, starting at 0.
+func every(n, step int) []int {
+	seconds := make([]int, n)
+	for i := range seconds {
+		seconds[i] = i * step
+	}
+	return seconds
+}
+
+// listed is a shape listing rows[i] in snapshot i.
+func listed(rows ...[]parser.ProcessData) func(i int, s *parser.Snapshot) {
+	return func(i int, s *parser.Snapshot) { s.Processes = rows[i] }
+}
+
+func TestThreads_ZeroFillsMissingSnapshots(t *testing.T) {
+	data := capture(every(2, 2), listed(
+		[]parser.ProcessData{{PID: 1, Command: "a", CPU: 10, S: "R"}},
+		[]parser.ProcessData{{PID: 2, Command: "b", CPU: 20, S: "S"}},
+	))
+	threads := Threads(data)
+	if len(threads) != 2 || threads[0].TID != 1 || threads[1].TID != 2 {
+		t.Fatalf("unexpected threads: %+v", threads)
+	}
+	if threads[0].CPU[1] != 0 || threads[0].Present[1] || threads[0].Samples() != 1 {
+		t.Errorf("thread 1 should be absent from the second snapshot: %+v", threads[0])
+	}
+}
+
+func TestComputeThreadStats(t *testing.T) {
+	data := capture(every(4, 2), listed(
+		[]parser.ProcessData{{PID: 1, Command: "hot", User: "dremio", CPU: 100, S: "R"}, {PID: 2, Command: "cold", CPU: 10, S: "S"}},
+		[]parser.ProcessData{{PID: 1, Command: "hot", User: "dremio", CPU: 50, S: "R"}},
+		[]parser.ProcessData{{PID: 1, Command: "hot", User: "dremio", CPU: 0, S: "S"}},
+		nil,
+	))
+	stats := ComputeThreadStats(data)
+	if len(stats) != 2 {
+		t.Fatalf("got %d thread stats, want 2", len(stats))
+	}
+	hot := stats[0]
+	if hot.TID != 1 || hot.User != "dremio" || hot.Samples != 3 {
+		t.Errorf("unexpected hot thread stats: %+v", hot)
+	}
+	if hot.CPU.Max != 100 || hot.CPU.Min != 0 || hot.CPU.Median != 50 {
+		t.Errorf("unexpected CPU summary: %+v", hot.CPU)
+	}
+	// 100% for 2s + 50% for 2s
+	if math.Abs(hot.CPUSeconds-3) > 1e-9 {
+		t.Errorf("CPUSeconds = %v, want 3", hot.CPUSeconds)
+	}
+	if hot.RunningFraction != 0.5 {
+		t.Errorf("RunningFraction = %v, want 0.5", hot.RunningFraction)
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	data := capture([]int{0, 2, 5}, nil)
+	want := []time.Duration{2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, got := range Intervals(data) {
+		if got != want[i] {
+			t.Errorf("interval %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// RankMetric selects how threads are ranked when picking the top N.
+type RankMetric string
+
+const (
+	RankAverage    RankMetric = "avg"         // mean %CPU over the whole capture
+	RankPeak       RankMetric = "peak"        // highest %CPU in any snapshot
+	RankCPUSeconds RankMetric = "cpu-seconds" // estimated CPU time consumed
+)
+
+// ParseRankMetric validates a ranking metric name.
+func ParseRankMetric(s string) (RankMetric, error) {
+	switch m := RankMetric(s); m {
+	case RankAverage, RankPeak, RankCPUSeconds:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown ranking metric %q (want avg, peak or cpu-seconds)", s)
+	}
+}
+
+// TopThreads returns the n highest ranked threads and the summed %CPU of all
+// remaining threads per snapshot, so totals still add up. When n is not
+// positive or covers every thread, all threads are returned and other is nil.
+func TopThreads(data parser.ReportData, n int, metric RankMetric) (top []ThreadSeries, other []float64) {
+	top, _, other = SplitTopThreads(data, n, metric)
+	return top, other
+}
+
+// SplitTopThreads is TopThreads that also returns the remaining threads,
+// still in rank order, which other sums.
+func SplitTopThreads(data parser.ReportData, n int, metric RankMetric) (top, rest []ThreadSeries, other []float64) {
+	threads := Threads(data)
+	intervals := Intervals(data)
+	score := func(t ThreadSeries) float64 {
+		switch metric {
+		case RankPeak:
+			m := 0.0
+			for _, v := range t.CPU {
+				if v > m {
+					m = v
+				}
+			}
+			return m
+		case RankCPUSeconds:
+			var s float64
+			for i, v := range t.CPU {
+				s += v / 100 * intervals[i].Seconds()
+			}
+			return s
+		default:
+			return Mean(t.CPU)
+		}
+	}
+
+	scores := make(map[int]float64, len(threads))
+	for _, t := range threads {
+		scores[t.TID] = score(t)
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		return scores[threads[i].TID] > scores[threads[j].TID]
+	})
+	if n <= 0 || n >= len(threads) {
+		return threads, nil, nil
+	}
+
+	other = make([]float64, len(data.Snapshots))
+	for _, t := range threads[n:] {
+		for i, v := range t.CPU {
+			other[i] += v
+		}
+	}
+	return threads[:n], threads[n:], other
+}
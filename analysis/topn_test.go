@@ -0,0 +1,73 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func topNData() parser.ReportData {
+	return capture(every(3, 2), listed(
+		[]parser.ProcessData{
+			{PID: 1, Command: "steady", CPU: 40},
+			{PID: 2, Command: "spiky", CPU: 0},
+			{PID: 3, Command: "idle", CPU: 1},
+		},
+		[]parser.ProcessData{
+			{PID: 1, Command: "steady", CPU: 40},
+			{PID: 2, Command: "spiky", CPU: 90},
+			{PID: 3, Command: "idle", CPU: 2},
+		},
+		[]parser.ProcessData{
+			{PID: 1, Command: "steady", CPU: 40},
+		},
+	))
+}
+
+func TestTopThreads(t *testing.T) {
+	top, other := TopThreads(topNData(), 1, RankAverage)
+	if len(top) != 1 || top[0].TID != 1 {
+		t.Fatalf("avg ranking picked %+v, want TID 1", top)
+	}
+	want := []float64{1, 92, 0}
+	for i := range want {
+		if other[i] != want[i] {
+			t.Errorf("other[%d] = %v, want %v", i, other[i], want[i])
+		}
+	}
+
+	top, _ = TopThreads(topNData(), 1, RankPeak)
+	if top[0].TID != 2 {
+		t.Errorf("peak ranking picked TID %d, want 2", top[0].TID)
+	}
+	top, _ = TopThreads(topNData(), 1, RankCPUSeconds)
+	if top[0].TID != 1 {
+		t.Errorf("cpu-seconds ranking picked TID %d, want 1", top[0].TID)
+	}
+}
+
+func TestTopThreads_All(t *testing.T) {
+	top, other := TopThreads(topNData(), 0, RankAverage)
+	if len(top) != 3 || other != nil {
+		t.Errorf("expected all threads and no other series, got %d threads, other=%v", len(top), other)
+	}
+}
+
+func TestSplitTopThreads(t *testing.T) {
+	top, rest, _ := SplitTopThreads(topNData(), 1, RankAverage)
+	if len(top) != 1 || len(rest) != 2 || rest[0].TID != 2 || rest[1].TID != 3 {
+		t.Errorf("expected the remaining threads in rank order, got top %+v rest %+v", top, rest)
+	}
+	if _, rest, _ = SplitTopThreads(topNData(), 0, RankAverage); rest != nil {
+		t.Errorf("expected no remaining threads, got %+v", rest)
+	}
+}
+
+func TestParseRankMetric(t *testing.T) {
+	if _, err := ParseRankMetric("peak"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := ParseRankMetric("median"); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
@@ -0,0 +1,116 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// Window selects part of a capture. From and To are inclusive bounds given as
+// a wall-clock time ("15:04" or "15:04:05"), an offset from the first
+// snapshot ("10m" or "+10m"), or an offset back from the last snapshot
+// ("-10m"). Skip and Limit are applied after them, dropping the first Skip
+// snapshots and keeping at most Limit of the rest.
+type Window struct {
+	From  string
+	To    string
+	Skip  int
+	Limit int // 0 keeps every snapshot
+}
+
+// IsZero reports whether w selects the whole capture.
+func (w Window) IsZero() bool {
+	return w.From == "" && w.To == "" && w.Skip == 0 && w.Limit == 0
+}
+
+// SelectWindow returns the snapshots of data inside w. It fails when a bound
+// cannot be parsed or nothing is left.
+func SelectWindow(data parser.ReportData, w Window) (parser.ReportData, error) {
+	if w.Skip < 0 || w.Limit < 0 {
+		return parser.ReportData{}, fmt.Errorf("skip and limit must not be negative")
+	}
+	if w.IsZero() || len(data.Snapshots) == 0 {
+		return data, nil
+	}
+	offsets := data.Offsets()
+	from, to := time.Duration(0), offsets[len(offsets)-1]
+	var err error
+	if w.From != "" {
+		if from, err = windowBound(w.From, data, offsets); err != nil {
+			return parser.ReportData{}, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if w.To != "" {
+		if to, err = windowBound(w.To, data, offsets); err != nil {
+			return parser.ReportData{}, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+	if w.From != "" && w.To != "" && from > to {
+		return parser.ReportData{}, fmt.Errorf("from %s is after to %s", w.From, w.To)
+	}
+
+	var selected []parser.Snapshot
+	for i, s := range data.Snapshots {
+		if offsets[i] >= from && offsets[i] <= to {
+			selected = append(selected, s)
+		}
+	}
+	if w.Skip >= len(selected) {
+		selected = nil
+	} else {
+		selected = selected[w.Skip:]
+	}
+	if w.Limit > 0 && len(selected) > w.Limit {
+		selected = selected[:w.Limit]
+	}
+	if len(selected) == 0 {
+		return parser.ReportData{}, fmt.Errorf("window selects none of the %d snapshots", len(data.Snapshots))
+	}
+	return parser.ReportData{Snapshots: selected}, nil
+}
+
+// windowBound resolves a From or To value to an offset from the first
+// snapshot.
+func windowBound(spec string, data parser.ReportData, offsets []time.Duration) (time.Duration, error) {
+	if rest, ok := strings.CutPrefix(spec, "-"); ok {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, err
+		}
+		return offsets[len(offsets)-1] - d, nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(spec, "+")); err == nil {
+		return d, nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		t, err := time.Parse(layout, spec)
+		if err != nil {
+			continue
+		}
+		// top only prints the time of day, so a time before the first
+		// snapshot's belongs to the next day
+		d := clock(t) - clock(data.Snapshots[0].Time)
+		if d < 0 {
+			d += 24 * time.Hour
+		}
+		return d, nil
+	}
+	return 0, fmt.Errorf("%q is neither a time of day (15:04:05) nor an offset (10m, -10m)", spec)
+}
+
+func clock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// DescribeWindow summarizes a selection for the report header, e.g.
+// "12:05:00 – 12:15:00, 121 of 1440 snapshots".
+func DescribeWindow(selected parser.ReportData, total int) string {
+	if len(selected.Snapshots) == 0 {
+		return fmt.Sprintf("0 of %d snapshots", total)
+	}
+	first := selected.Snapshots[0].Time.Format("15:04:05")
+	last := selected.Snapshots[len(selected.Snapshots)-1].Time.Format("15:04:05")
+	return fmt.Sprintf("%s – %s, %d of %d snapshots", first, last, len(selected.Snapshots), total)
+}
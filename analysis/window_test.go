@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// windowCapture has a snapshot every minute from 23:55 to 00:04, crossing
+// midnight.
+func windowCapture() parser.ReportData {
+	return capture(every(10, 60), func(_ int, s *parser.Snapshot) {
+		t := s.Time.Add(11*time.Hour + 55*time.Minute)
+		// top prints the time of day only
+		s.Time = time.Date(0, 1, 1, t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	})
+}
+
+func TestSelectWindow(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		w           Window
+		first, last string
+		n           int
+	}{
+		{"whole capture", Window{}, "23:55:00", "00:04:00", 10},
+		{"wall clock across midnight", Window{From: "23:58", To: "00:01:00"}, "23:58:00", "00:01:00", 4},
+		{"offsets", Window{From: "2m", To: "+4m"}, "23:57:00", "23:59:00", 3},
+		{"from the end", Window{From: "-3m"}, "00:01:00", "00:04:00", 4},
+		{"skip and limit", Window{From: "1m", Skip: 2, Limit: 3}, "23:58:00", "00:00:00", 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SelectWindow(windowCapture(), tc.w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			snaps := got.Snapshots
+			if len(snaps) != tc.n || snaps[0].Time.Format("15:04:05") != tc.first || snaps[len(snaps)-1].Time.Format("15:04:05") != tc.last {
+				t.Errorf("expected %d snapshots %s – %s, got %d %s – %s", tc.n, tc.first, tc.last,
+					len(snaps), snaps[0].Time.Format("15:04:05"), snaps[len(snaps)-1].Time.Format("15:04:05"))
+			}
+		})
+	}
+}
+
+func TestSelectWindow_Errors(t *testing.T) {
+	for _, w := range []Window{
+		{From: "noon"},
+		{To: "-soon"},
+		{From: "5m", To: "2m"},
+		{Skip: 10},
+		{Limit: -1},
+	} {
+		if _, err := SelectWindow(windowCapture(), w); err == nil {
+			t.Errorf("expected an error for %+v", w)
+		}
+	}
+}
+
+func TestDescribeWindow(t *testing.T) {
+	selected, err := SelectWindow(windowCapture(), Window{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := DescribeWindow(selected, 10), "23:55:00 – 23:56:00, 2 of 10 snapshots"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	flag "github.com/spf13/pflag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// runBaseline implements `ttoprep baseline <file>...`, saving the normal
+// range of system metrics and per-pool CPU of known good captures.
+func runBaseline(args []string) {
+	fs := flag.NewFlagSet("baseline", flag.ExitOnError)
+	output := fs.StringP("output", "o", "baseline.json", "Output profile file path")
+	pools := fs.String("pools", "", "JSON file configuring how thread names are normalized into pools")
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if fs.NArg() < 1 {
+		log.Fatal("Please provide at least one input file")
+	}
+
+	var normalizer *analysis.Normalizer
+	if *pools != "" {
+		var err error
+		if normalizer, err = loadPoolConfig(*pools); err != nil {
+			log.Fatal(err)
+		}
+	}
+	var captures []parser.ReportData
+	for _, path := range fs.Args() {
+		data, err := readInput(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		parsed, err := parser.ParseTopOutput(data)
+		if err != nil {
+			log.Fatalf("Error parsing %s: %v", path, err)
+		}
+		captures = append(captures, parsed)
+	}
+	profile := analysis.BuildProfile(captures, normalizer)
+
+	outPath := filepath.Clean(*output)
+	if strings.Contains(outPath, "..") {
+		log.Fatalf("invalid output path: %s", *output)
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("Error creating profile: %v", err)
+	}
+	if err := analysis.WriteProfile(f, profile); err != nil {
+		_ = f.Close()
+		log.Fatalf("Error writing profile: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Error closing profile: %v", err)
+	}
+	fmt.Printf("baseline of %d snapshots from %d captures written to %s\n", profile.Snapshots, profile.Captures, outPath)
+}
+
+// loadBaseline reads a baseline profile from path.
+func loadBaseline(path string) (*analysis.Profile, error) {
+	raw, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := analysis.ReadProfile(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	flag "github.com/spf13/pflag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// runCheck implements `ttoprep check --rules rules.json <file>`, evaluating
+// declarative rules against a capture and exiting non-zero on violations so
+// it can gate CI jobs.
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	rules := fs.String("rules", "", "JSON file with the rules to evaluate (required)")
+	junit := fs.String("junit", "", "Also write the results as JUnit XML to this file")
+	pools := fs.String("pools", "", "JSON file configuring how thread names are normalized into pools")
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if *rules == "" || fs.NArg() != 1 {
+		log.Fatal("Usage: ttoprep check --rules <rules.json> [--junit <file>] <file>")
+	}
+
+	raw, err := readInput(*rules)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := analysis.ReadCheckConfig(bytes.NewReader(raw))
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", *rules, err)
+	}
+	var normalizer *analysis.Normalizer
+	if *pools != "" {
+		if normalizer, err = loadPoolConfig(*pools); err != nil {
+			log.Fatal(err)
+		}
+	}
+	path := fs.Arg(0)
+	data, err := readInput(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parsed, err := parser.ParseTopOutput(data)
+	if err != nil {
+		log.Fatalf("Error parsing %s: %v", path, err)
+	}
+	results, err := analysis.EvaluateChecks(parsed, cfg, normalizer)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := 0
+	for _, r := range results {
+		name := r.Rule.Name
+		if name == "" {
+			name = r.Rule.Describe()
+		}
+		if r.Passed() {
+			fmt.Printf("PASS %s\n", name)
+			continue
+		}
+		failed++
+		if r.Rule.Name != "" {
+			name += ": " + r.Rule.Describe()
+		}
+		fmt.Printf("FAIL %s\n", name)
+		for _, v := range r.Violations {
+			fmt.Printf("  %s (%s – %s)\n", v.Message,
+				parsed.Snapshots[v.Start].Time.Format("15:04:05"), parsed.Snapshots[v.End].Time.Format("15:04:05"))
+		}
+	}
+
+	if *junit != "" {
+		outPath := filepath.Clean(*junit)
+		if strings.Contains(outPath, "..") {
+			log.Fatalf("invalid output path: %s", *junit)
+		}
+		f, err := os.Create(outPath)
+		if err != nil {
+			log.Fatalf("Error creating JUnit report: %v", err)
+		}
+		if err := analysis.WriteJUnit(f, filepath.Base(path), results); err != nil {
+			_ = f.Close()
+			log.Fatalf("Error writing JUnit report: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatalf("Error closing JUnit report: %v", err)
+		}
+	}
+
+	fmt.Printf("%d of %d rules failed\n", failed, len(results))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
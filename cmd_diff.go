@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	flag "github.com/spf13/pflag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+	"github.com/rsvihladremio/threaded-top-reporter/reporter"
+)
+
+// runDiff implements `ttoprep diff <before> <after>`, comparing two captures
+// as an HTML report and optionally as markdown.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	output := fs.StringP("output", "o", "ttop-diff.html", "Output HTML file path")
+	name := fs.StringP("name", "n", "Threaded Top Diff", "Report title")
+	markdown := fs.String("markdown", "", "Also write the comparison as markdown to this file (\"-\" for stdout)")
+	cdn := fs.Bool("cdn", false, "Load Bootstrap and ECharts from the CDN (with SRI hashes) instead of inlining them")
+	pools := fs.String("pools", "", "JSON file configuring how thread names are normalized into pools")
+	top := fs.Int("top", 20, "Number of regressions and improvements to list")
+	minDelta := fs.Float64("min-delta", 1, "Hide thread and pool CPU changes smaller than this many %CPU")
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if fs.NArg() < 2 {
+		log.Fatal("Please provide the before and after input files")
+	}
+
+	before, err := loadDiffInput(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	after, err := loadDiffInput(fs.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := reporter.DiffOptions{CDN: *cdn, Top: *top, MinDelta: *minDelta}
+	if *pools != "" {
+		if opts.Pools, err = loadPoolConfig(*pools); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := reporter.GenerateDiffReport(before, after, *output, *name, Version, opts); err != nil {
+		log.Fatalf("Error generating diff report: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "diff report written to %s\n", *output)
+
+	switch *markdown {
+	case "":
+	case "-":
+		if err := reporter.WriteDiffMarkdown(os.Stdout, before, after, opts); err != nil {
+			log.Fatalf("Error writing markdown: %v", err)
+		}
+	default:
+		mdPath := filepath.Clean(*markdown)
+		if strings.Contains(mdPath, "..") {
+			log.Fatalf("invalid markdown path: %s", *markdown)
+		}
+		f, err := os.Create(mdPath)
+		if err != nil {
+			log.Fatalf("Error creating markdown file: %v", err)
+		}
+		if err := reporter.WriteDiffMarkdown(f, before, after, opts); err != nil {
+			_ = f.Close()
+			log.Fatalf("Error writing markdown: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatalf("Error closing markdown file: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "markdown written to %s\n", mdPath)
+	}
+}
+
+// loadDiffInput reads and parses one side of a diff.
+func loadDiffInput(path string) (reporter.DiffInput, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return reporter.DiffInput{}, err
+	}
+	parsed, err := parser.ParseTopOutput(data)
+	if err != nil {
+		return reporter.DiffInput{}, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return reporter.DiffInput{
+		Data:     parsed,
+		FileName: filepath.Base(filepath.Clean(path)),
+		FileHash: fmt.Sprintf("%x", sha256.Sum256(data)),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	flag "github.com/spf13/pflag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/rsvihladremio/threaded-top-reporter/reporter"
+)
+
+// runExtract implements `ttoprep extract report.html`, recovering the capture
+// embedded with --embed-capture and verifying it against the recorded hash.
+func runExtract(args []string) {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	output := fs.StringP("output", "o", "", "Where to write the capture (defaults to its original file name)")
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if fs.NArg() < 1 {
+		log.Fatal("Please provide a report file")
+	}
+
+	report, err := readInput(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fileName, data, err := reporter.ExtractCapture(report)
+	if err != nil {
+		log.Fatalf("Error extracting capture: %v", err)
+	}
+
+	outPath := *output
+	if outPath == "" {
+		// only trust the base name recorded in the report
+		outPath = filepath.Base(fileName)
+		if outPath == "." || outPath == string(filepath.Separator) {
+			outPath = "capture.txt"
+		}
+		if _, err := os.Stat(outPath); err == nil {
+			log.Fatalf("%s already exists, use -o to choose another path", outPath)
+		}
+	}
+	if err := os.WriteFile(filepath.Clean(outPath), data, 0600); err != nil {
+		log.Fatalf("Error writing capture: %v", err)
+	}
+	fmt.Printf("capture verified and written to %s\n", outPath)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	flag "github.com/spf13/pflag"
+	"log"
+	"os"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+	"github.com/rsvihladremio/threaded-top-reporter/summary"
+)
+
+// runSummary implements `ttoprep summary <file>`, printing a compact report to stdout.
+func runSummary(args []string) {
+	fs := flag.NewFlagSet("summary", flag.ExitOnError)
+	width := fs.IntP("width", "w", 60, "Maximum sparkline width in characters")
+	top := fs.IntP("top", "t", 10, "Number of hottest threads to list")
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if fs.NArg() < 1 {
+		log.Fatal("Please provide an input file")
+	}
+
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	parsedData, err := parser.ParseTopOutput(data)
+	if err != nil {
+		log.Fatalf("Error parsing top output: %v", err)
+	}
+
+	opts := summary.Options{
+		Color: isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+		Width: *width,
+		Top:   *top,
+	}
+	if err := summary.Write(os.Stdout, parsedData, opts); err != nil {
+		log.Fatalf("Error writing summary: %v", err)
+	}
+}
+
+// isTerminal reports whether f is attached to a character device such as a TTY.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	flag "github.com/spf13/pflag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+	"github.com/rsvihladremio/threaded-top-reporter/traceevent"
+)
+
+// runTrace implements `ttoprep trace <file>`, exporting thread activity as
+// Trace Event JSON for Perfetto UI and chrome://tracing.
+func runTrace(args []string) {
+	fs := flag.NewFlagSet("trace", flag.ExitOnError)
+	output := fs.StringP("output", "o", "ttop.trace.json", "Output trace file path")
+	if err := fs.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+	if fs.NArg() < 1 {
+		log.Fatal("Please provide an input file")
+	}
+
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	parsedData, err := parser.ParseTopOutput(data)
+	if err != nil {
+		log.Fatalf("Error parsing top output: %v", err)
+	}
+
+	outPath := filepath.Clean(*output)
+	if strings.Contains(outPath, "..") {
+		log.Fatalf("invalid output path: %s", *output)
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		log.Fatalf("Error creating trace file: %v", err)
+	}
+	if err := traceevent.Write(f, parsedData, filepath.Base(filepath.Clean(fs.Arg(0)))); err != nil {
+		_ = f.Close()
+		log.Fatalf("Error writing trace: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Error closing trace file: %v", err)
+	}
+	fmt.Printf("trace written to %s\n", outPath)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	flag "github.com/spf13/pflag"
@@ -8,7 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
 	"github.com/rsvihladremio/threaded-top-reporter/parser" // Import the parser package
 	"github.com/rsvihladremio/threaded-top-reporter/reporter"
 )
@@ -17,13 +20,84 @@ var (
 	outputFile  string
 	reportTitle string
 	metadata    string
+	useCDN      bool
+	embedInput  bool
+	noJS        bool
+	svgDir      string
+	poolsFile   string
+	categories  string
+	baseline    string
+	topN        int
+	rankBy      string
+	anomalyCfg  analysis.AnomalyConfig
+	anomalyBy   string
+	saturation  analysis.SaturationConfig
+	window      analysis.Window
+	filterCfg   analysis.FilterConfig
 	Version     string = "dev" // overridden via -ldflags "-X main.Version=…"
 )
 
-func init() {
+// htmlOnlyFlags configure sections of the interactive report that the
+// --no-js report does not have.
+var htmlOnlyFlags = []string{
+	"cdn", "embed-capture", "pools", "categories", "baseline",
+	"anomaly-method", "anomaly-threshold", "anomaly-window",
+	"cores", "pegged-cpu", "saturation-duration",
+}
+
+func main() {
+	// Subcommands are dispatched before flag parsing so each one can own its flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "summary":
+			runSummary(os.Args[2:])
+			return
+		case "extract":
+			runExtract(os.Args[2:])
+			return
+		case "trace":
+			runTrace(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "baseline":
+			runBaseline(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		}
+	}
+
 	flag.StringVarP(&outputFile, "output", "o", "ttop.html", "Output HTML file path")
 	flag.StringVarP(&reportTitle, "name", "n", "Threaded Top Report", "Report title")
 	flag.StringVarP(&metadata, "metadata", "m", "", "Additional metadata as JSON string")
+	flag.BoolVar(&useCDN, "cdn", false, "Load Bootstrap and ECharts from the CDN (with SRI hashes) instead of inlining them")
+	flag.BoolVar(&embedInput, "embed-capture", false, "Embed the compressed input in the report so it can be downloaded or extracted later")
+	flag.BoolVar(&noJS, "no-js", false, "Write a script-free report with the charts rendered as static SVG")
+	flag.StringVar(&svgDir, "svg-dir", "", "Also write each chart as a standalone .svg file into this directory")
+	flag.StringVar(&poolsFile, "pools", "", "JSON file configuring how thread names are normalized into pools")
+	flag.StringVar(&categories, "categories", "", "JSON file with extra rules classifying threads into categories such as GC or JIT")
+	flag.StringVar(&baseline, "baseline", "", "Baseline profile written by \"ttoprep baseline\" to score this capture against")
+	flag.IntVar(&topN, "top", 20, "Limit the per-thread CPU and attribution charts to the top N threads or pools plus an \"other\" series (0 shows all)")
+	flag.StringVar(&rankBy, "rank", "avg", "Metric used to pick the top threads: avg, peak or cpu-seconds")
+	flag.StringVar(&anomalyBy, "anomaly-method", "mad", "Anomaly scoring against the rolling baseline: mad or zscore")
+	flag.Float64Var(&anomalyCfg.Threshold, "anomaly-threshold", 3.5, "Score above which a snapshot is flagged as anomalous")
+	flag.IntVar(&anomalyCfg.Window, "anomaly-window", 10, "Number of preceding snapshots forming the rolling baseline")
+	flag.IntVar(&saturation.Cores, "cores", 0, "Host core count for saturation analysis (default: from per-core lines or the metadata)")
+	flag.Float64Var(&saturation.PeggedCPU, "pegged-cpu", 95, "%CPU at or above which a thread counts as pegged")
+	flag.DurationVar(&saturation.MinDuration, "saturation-duration", 30*time.Second, "How long a thread must stay pegged or the host saturated to be reported")
+	flag.StringVar(&window.From, "from", "", "Start of the window to report on: a time of day (15:04:05), an offset from the first snapshot (10m) or from the last (-10m)")
+	flag.StringVar(&window.To, "to", "", "End of the window to report on, in the same formats as --from")
+	flag.IntVar(&window.Skip, "skip", 0, "Skip this many snapshots at the start of the window")
+	flag.IntVar(&window.Limit, "limit", 0, "Report on at most this many snapshots (0 keeps all)")
+	flag.StringSliceVar(&filterCfg.Users, "user", nil, "Only report threads owned by these users")
+	flag.IntSliceVar(&filterCfg.TIDs, "tid", nil, "Only report these thread IDs")
+	flag.StringVar(&filterCfg.Command, "command-regex", "", "Only report threads whose name matches this regular expression")
+	flag.StringVar(&filterCfg.ExcludeCommand, "exclude-command-regex", "", "Hide threads whose name matches this regular expression")
+	flag.StringSliceVar(&filterCfg.States, "state", nil, "Only report threads seen in one of these states, e.g. R,D")
+	flag.Float64Var(&filterCfg.MinCPU, "min-cpu", 0, "Hide threads whose %CPU never reaches this")
 	showVersion := flag.BoolP("version", "V", false, "show version and exit")
 
 	flag.Parse()
@@ -32,9 +106,19 @@ func init() {
 		fmt.Println(Version)
 		os.Exit(0)
 	}
-}
 
-func main() {
+	// The script-free report has no room for the interactive-only sections
+	if noJS {
+		for _, name := range htmlOnlyFlags {
+			if flag.CommandLine.Changed(name) {
+				log.Fatalf("--%s is not supported with --no-js", name)
+			}
+		}
+	}
+	rank, err := analysis.ParseRankMetric(rankBy)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Validate input file
 	args := flag.Args()
@@ -43,14 +127,9 @@ func main() {
 	}
 	inputFile := args[0]
 
-	// Sanitize and read input file
-	cleanInput := filepath.Clean(inputFile)
-	if strings.Contains(cleanInput, "..") {
-		log.Fatalf("invalid input path: %s", inputFile)
-	}
-	data, err := os.ReadFile(cleanInput)
+	data, err := readInput(inputFile)
 	if err != nil {
-		log.Fatalf("Error reading input file: %v", err)
+		log.Fatal(err)
 	}
 
 	// Parse input using the new package
@@ -58,13 +137,104 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error parsing top output: %v", err)
 	}
+	var windowDesc string
+	if !window.IsZero() {
+		total := len(parsedData.Snapshots)
+		if parsedData, err = analysis.SelectWindow(parsedData, window); err != nil {
+			log.Fatalf("Error selecting window: %v", err)
+		}
+		windowDesc = analysis.DescribeWindow(parsedData, total)
+	}
+	threadFilter, err := analysis.NewThreadFilter(filterCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var hidden int
+	parsedData, hidden = threadFilter.Apply(parsedData)
 
 	// Generate report
-	fileName := filepath.Base(cleanInput)
+	fileName := filepath.Base(filepath.Clean(inputFile))
 	fileHash := fmt.Sprintf("%x", sha256.Sum256(data))
-	if err := reporter.GenerateReport(parsedData, outputFile, reportTitle, metadata, fileName, fileHash, Version); err != nil {
+	staticOpts := reporter.StaticOptions{TopN: topN, Rank: rank, Window: windowDesc,
+		Filters: threadFilter.Describe(), HiddenThreads: hidden}
+	if noJS {
+		err = reporter.GenerateStaticReport(parsedData, outputFile, reportTitle, metadata, fileName, fileHash, Version, staticOpts)
+	} else {
+		opts := reporter.Options{CDN: useCDN, TopN: topN, Rank: rank, Anomalies: anomalyCfg, Saturation: saturation, Window: windowDesc,
+			Filters: threadFilter.Describe(), HiddenThreads: hidden}
+		if opts.Anomalies.Method, err = analysis.ParseAnomalyMethod(anomalyBy); err != nil {
+			log.Fatal(err)
+		}
+		if poolsFile != "" {
+			if opts.Pools, err = loadPoolConfig(poolsFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if categories != "" {
+			if opts.Categories, err = loadCategoryConfig(categories); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if baseline != "" {
+			if opts.Baseline, err = loadBaseline(baseline); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if embedInput {
+			opts.Capture = data
+		}
+		err = reporter.GenerateReportWithOptions(parsedData, outputFile, reportTitle, metadata, fileName, fileHash, Version, opts)
+	}
+	if err != nil {
 		log.Fatalf("Error generating report: %v", err)
 	}
 
+	if svgDir != "" {
+		files, err := reporter.WriteSVGCharts(parsedData, svgDir, staticOpts)
+		if err != nil {
+			log.Fatalf("Error writing SVG charts: %v", err)
+		}
+		fmt.Printf("%d charts written to %s\n", len(files), svgDir)
+	}
+
 	fmt.Printf("report '%s' written to %s\n", reportTitle, outputFile)
 }
+
+// readInput sanitizes the input path and reads the capture into memory.
+func readInput(inputFile string) ([]byte, error) {
+	cleanInput := filepath.Clean(inputFile)
+	if strings.Contains(cleanInput, "..") {
+		return nil, fmt.Errorf("invalid input path: %s", inputFile)
+	}
+	data, err := os.ReadFile(cleanInput)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input file: %v", err)
+	}
+	return data, nil
+}
+
+// loadPoolConfig reads a pool normalization config from path.
+func loadPoolConfig(path string) (*analysis.Normalizer, error) {
+	raw, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := analysis.ReadPoolConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return analysis.NewNormalizer(cfg)
+}
+
+// loadCategoryConfig reads thread category rules from path.
+func loadCategoryConfig(path string) (*analysis.Classifier, error) {
+	raw, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := analysis.ReadCategoryConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	return analysis.NewClassifier(cfg)
+}
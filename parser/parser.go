@@ -33,6 +33,7 @@ type Metadata struct {
 	MemFree         float64
 	MemUsed         float64
 	MemBuffCache    float64
+	MemAvail        float64
 	SwapTotal       float64
 	SwapFree        float64
 	SwapUsed        float64
@@ -41,6 +42,9 @@ type Metadata struct {
 	LoadAvg15       float64
 	Uptime          string
 	Users           int
+	// CPUCores is the number of per-core "%CpuN" lines, printed when top's
+	// per-CPU view is on; 0 when only the "%Cpu(s)" summary was captured.
+	CPUCores int
 }
 
 // Snapshot holds data for a single 'top' output snapshot.
@@ -66,6 +70,31 @@ type ProcessData struct {
 	Command string
 }
 
+// perCoreRegex matches the per-core CPU lines top prints after pressing "1",
+// e.g. "%Cpu0  :  2.0 us,  1.0 sy, ...".
+var perCoreRegex = regexp.MustCompile(`^%Cpu\d+\s*:`)
+
+// Offsets returns the time of each snapshot relative to the first one. top only
+// prints the time of day, so whenever the clock goes backwards the capture is
+// assumed to have crossed midnight and a day is added.
+func (r ReportData) Offsets() []time.Duration {
+	offsets := make([]time.Duration, len(r.Snapshots))
+	if len(r.Snapshots) == 0 {
+		return offsets
+	}
+	start := r.Snapshots[0].Time
+	var days time.Duration
+	for i, s := range r.Snapshots {
+		offset := s.Time.Sub(start) + days
+		if i > 0 && offset < offsets[i-1] {
+			days += 24 * time.Hour
+			offset += 24 * time.Hour
+		}
+		offsets[i] = offset
+	}
+	return offsets
+}
+
 // ParseTopOutput parses the raw top output and returns structured data.
 func ParseTopOutput(data []byte) (ReportData, error) {
 	reader := bufio.NewReader(bytes.NewReader(data))
@@ -148,6 +177,12 @@ func ParseTopOutput(data []byte) (ReportData, error) {
 			continue
 		}
 
+		// Per-core CPU lines only tell us how many cores the host has
+		if perCoreRegex.MatchString(line) {
+			currentSnapshot.Metadata.CPUCores++
+			continue
+		}
+
 		// Check for header line (PID USER ...) and skip
 		if strings.HasPrefix(line, "PID") {
 			continue
@@ -230,10 +265,10 @@ func parseMetadata(line string, metadata *Metadata) error {
 		}
 
 	case key == "MiB Swap":
-		// allow the trailing dot after "used."
-		_, err := fmt.Sscanf(value, "%f total, %f free, %f used.",
-			&metadata.SwapTotal, &metadata.SwapFree, &metadata.SwapUsed)
-		if err != nil {
+		// allow the trailing dot after "used."; older tops print no "avail Mem"
+		n, err := fmt.Sscanf(value, "%f total, %f free, %f used. %f avail Mem",
+			&metadata.SwapTotal, &metadata.SwapFree, &metadata.SwapUsed, &metadata.MemAvail)
+		if n < 3 {
 			return fmt.Errorf("error parsing Swap: %v", err)
 		}
 
@@ -261,6 +296,36 @@ func parseMetadata(line string, metadata *Metadata) error {
 	return nil
 }
 
+// ParseMemoryMiB converts a top memory column such as RES ("123456", "1.5g",
+// "512m") to MiB. Plain numbers are KiB, top's default unit.
+func ParseMemoryMiB(s string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty memory value")
+	}
+	scale := 1.0 / 1024 // KiB
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		s = s[:len(s)-1]
+	case 'm', 'M':
+		scale = 1
+		s = s[:len(s)-1]
+	case 'g', 'G':
+		scale = 1024
+		s = s[:len(s)-1]
+	case 't', 'T':
+		scale = 1024 * 1024
+		s = s[:len(s)-1]
+	case 'p', 'P':
+		scale = 1024 * 1024 * 1024
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory value %q: %w", s, err)
+	}
+	return v * scale, nil
+}
+
 func parseInt(s string) int {
 	i, err := strconv.Atoi(s)
 	if err != nil {
@@ -79,6 +79,7 @@ func TestParseTopOutput(t *testing.T) {
 				MemFree:         10953.7,
 				MemUsed:         3713.5,
 				MemBuffCache:    1341.1,
+				MemAvail:        12032.0,
 				SwapTotal:       0.0,
 				SwapFree:        0.0,
 				SwapUsed:        0.0,
@@ -218,6 +219,7 @@ func TestParseTopOutput(t *testing.T) {
 					actual.MemFree != expected.MemFree ||
 					actual.MemUsed != expected.MemUsed ||
 					actual.MemBuffCache != expected.MemBuffCache ||
+					actual.MemAvail != expected.MemAvail ||
 					actual.SwapTotal != expected.SwapTotal ||
 					actual.SwapFree != expected.SwapFree ||
 					actual.SwapUsed != expected.SwapUsed ||
@@ -292,3 +294,73 @@ func TestParseLargeInput(t *testing.T) {
 // 		_, _ = ParseTopOutput(data)
 // 	})
 // }
+
+func TestOffsets(t *testing.T) {
+	at := func(h, m, s int) Snapshot {
+		return Snapshot{Time: time.Date(0, 1, 1, h, m, s, 0, time.UTC)}
+	}
+	data := ReportData{Snapshots: []Snapshot{at(23, 59, 58), at(23, 59, 59), at(0, 0, 1), at(0, 0, 3)}}
+	want := []time.Duration{0, time.Second, 3 * time.Second, 5 * time.Second}
+	got := data.Offsets()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("offset %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if len((ReportData{}).Offsets()) != 0 {
+		t.Error("expected no offsets for empty data")
+	}
+}
+
+func TestParseTopOutput_PerCoreLines(t *testing.T) {
+	input := `top - 12:00:00 up 1 day,  2 users,  load average: 1.00, 1.00, 1.00
+Threads: 10 total,   1 running,   9 sleeping,   0 stopped,   0 zombie
+%Cpu0  : 99.0 us,  1.0 sy,  0.0 ni,  0.0 id,  0.0 wa,  0.0 hi,  0.0 si,  0.0 st
+%Cpu1  :  1.0 us,  0.0 sy,  0.0 ni, 99.0 id,  0.0 wa,  0.0 hi,  0.0 si,  0.0 st
+MiB Mem :  15000.0 total,   1000.0 free,  10000.0 used,   4000.0 buff/cache
+
+    PID USER      PR  NI    VIRT    RES    SHR S  %CPU  %MEM     TIME+ COMMAND
+    101 user      20   0   10.0g   1.0g  10000 R  99.0   1.0   1:00.00 planner
+`
+	data, err := ParseTopOutput([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Snapshots) != 1 || data.Snapshots[0].Metadata.CPUCores != 2 {
+		t.Fatalf("expected one snapshot with 2 cores, got %+v", data.Snapshots)
+	}
+	if len(data.Snapshots[0].Processes) != 1 {
+		t.Errorf("per-core lines should not be parsed as processes: %+v", data.Snapshots[0].Processes)
+	}
+}
+
+func TestParseMetadata_SwapWithoutAvail(t *testing.T) {
+	var m Metadata
+	if err := parseMetadata("MiB Swap:   2048.0 total,   1024.0 free,   1024.0 used.", &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.SwapUsed != 1024 || m.MemAvail != 0 {
+		t.Errorf("expected swap without available memory, got %+v", m)
+	}
+}
+
+func TestParseMemoryMiB(t *testing.T) {
+	tests := map[string]float64{
+		"2048":  2,
+		"512m":  512,
+		"1.5g":  1536,
+		"1t":    1024 * 1024,
+		"1024k": 1,
+	}
+	for in, want := range tests {
+		got, err := ParseMemoryMiB(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMemoryMiB(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "g", "abc"} {
+		if _, err := ParseMemoryMiB(in); err == nil {
+			t.Errorf("expected an error for %q", in)
+		}
+	}
+}
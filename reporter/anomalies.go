@@ -0,0 +1,67 @@
+package reporter
+
+import (
+	"fmt"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// AnomalyView is an anomaly as shown in the report list and chart annotations.
+type AnomalyView struct {
+	Chart       string  `json:"chart"` // element id of the chart it is drawn on
+	Start       int     `json:"start"`
+	End         int     `json:"end"`
+	Peak        int     `json:"peak"`
+	Value       float64 `json:"value"`
+	Label       string  `json:"label"`
+	TimeRange   string  `json:"-"`
+	Description string  `json:"-"`
+}
+
+// anomalyCharts maps each metric to the chart its annotations are drawn on.
+var anomalyCharts = map[string]string{
+	analysis.MetricTotalCPU:  "totalCpuChart",
+	analysis.MetricIOWait:    "totalCpuChart",
+	analysis.MetricSteal:     "totalCpuChart",
+	analysis.MetricLoad1:     "loadAvgChart",
+	analysis.MetricThreadCPU: "perProcessCpuChart",
+}
+
+// buildAnomalyViews converts detected anomalies for display, using times as
+// the x axis labels. Thread anomalies of threads folded into the per-thread
+// chart's "other" series are pinned on that series, as given by top and other,
+// with the thread named in the label.
+func buildAnomalyViews(anomalies []analysis.Anomaly, times []string, top []analysis.ThreadSeries, other []float64) []AnomalyView {
+	charted := make(map[int]bool, len(top))
+	for _, t := range top {
+		charted[t.TID] = true
+	}
+	var views []AnomalyView
+	for _, a := range anomalies {
+		subject := a.Metric
+		value := a.Value
+		label := ""
+		if a.Metric == analysis.MetricThreadCPU {
+			subject = fmt.Sprintf("%s (%d)", a.Command, a.TID)
+			if !charted[a.TID] && other != nil {
+				value = other[a.Peak]
+				label = "other: "
+			}
+		}
+		timeRange := times[a.Start]
+		if a.End != a.Start {
+			timeRange += " – " + times[a.End]
+		}
+		views = append(views, AnomalyView{
+			Chart:       anomalyCharts[a.Metric],
+			Start:       a.Start,
+			End:         a.End,
+			Peak:        a.Peak,
+			Value:       value,
+			Label:       fmt.Sprintf("%s%s %.1f", label, subject, a.Value),
+			TimeRange:   timeRange,
+			Description: fmt.Sprintf("%s peaked at %.1f against a baseline of %.1f (score %.1f)", subject, a.Value, a.Baseline, a.Score),
+		})
+	}
+	return views
+}
@@ -0,0 +1,37 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildAnomalyViews(t *testing.T) {
+	times := []string{"12:00:00", "12:00:02", "12:00:04"}
+	views := buildAnomalyViews([]analysis.Anomaly{
+		{Metric: analysis.MetricIOWait, Start: 1, End: 2, Peak: 2, Value: 40, Baseline: 1, Score: 12},
+		{Metric: analysis.MetricThreadCPU, TID: 7, Command: "worker", Start: 1, End: 1, Peak: 1, Value: 95},
+	}, times, nil, nil)
+	if views[0].Chart != "totalCpuChart" || views[0].TimeRange != "12:00:02 – 12:00:04" {
+		t.Errorf("unexpected iowait view: %+v", views[0])
+	}
+	if views[1].Chart != "perProcessCpuChart" || views[1].TimeRange != "12:00:02" || !strings.HasPrefix(views[1].Label, "worker (7)") {
+		t.Errorf("unexpected thread view: %+v", views[1])
+	}
+}
+
+func TestBuildAnomalyViews_FoldedThread(t *testing.T) {
+	times := []string{"12:00:00", "12:00:02"}
+	top := []analysis.ThreadSeries{{TID: 1, Command: "hot"}}
+	views := buildAnomalyViews([]analysis.Anomaly{
+		{Metric: analysis.MetricThreadCPU, TID: 1, Command: "hot", Start: 1, End: 1, Peak: 1, Value: 90},
+		{Metric: analysis.MetricThreadCPU, TID: 7, Command: "worker", Start: 1, End: 1, Peak: 1, Value: 60},
+	}, times, top, []float64{10, 75})
+	if views[0].Label != "hot (1) 90.0" || views[0].Value != 90 {
+		t.Errorf("charted thread should be pinned on its own line: %+v", views[0])
+	}
+	if views[1].Label != "other: worker (7) 60.0" || views[1].Value != 75 {
+		t.Errorf("folded thread should be pinned on the other series: %+v", views[1])
+	}
+}
@@ -0,0 +1,143 @@
+package reporter
+
+import (
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Vendored copies of the web assets are fetched by `make assets`; keep the
+// versions below in sync with the Makefile.
+//
+//go:embed assets
+var embeddedAssets embed.FS
+
+// assetFS is the file system the vendored assets are read from. Tests swap it
+// for an in-memory copy.
+var assetFS fs.FS = mustSub(embeddedAssets, "assets")
+
+type webAsset struct {
+	File string // path inside the assets directory
+	URL  string // pinned CDN location of the same file
+	// Integrity is the published SRI hash of the pinned CDN file, so --cdn
+	// reports need no vendored copy. When empty it is computed from the
+	// vendored file, and --cdn fails if that is missing too.
+	Integrity string
+}
+
+var (
+	stylesheets = []webAsset{
+		{File: "bootstrap.min.css", URL: "https://cdn.jsdelivr.net/npm/bootstrap@5.3.0/dist/css/bootstrap.min.css",
+			Integrity: "sha384-9ndCyUaIbzAi2FUVXJi0CjmCapSmO7SnpJef0486qhLnuZ2cdeRhO02iuK6FUUVM"},
+		{File: "bootstrap-icons.min.css", URL: "https://cdn.jsdelivr.net/npm/bootstrap-icons@1.11.0/font/bootstrap-icons.min.css"},
+	}
+	scripts = []webAsset{
+		{File: "bootstrap.bundle.min.js", URL: "https://cdn.jsdelivr.net/npm/bootstrap@5.3.0/dist/js/bootstrap.bundle.min.js",
+			Integrity: "sha384-geWF76RCwLtnZ8qwWowPQNguL3RmwHVBC9FhGdlKrxdiJJigb/j/68SIy3Te4Bkz"},
+		{File: "echarts.min.js", URL: "https://cdn.jsdelivr.net/npm/echarts@5.5.0/dist/echarts.min.js"},
+	}
+)
+
+// fontURLRegex matches the relative font references in bootstrap-icons.css
+var fontURLRegex = regexp.MustCompile(`url\("?\./fonts/([^"?)]+)(\?[^")]*)?"?\)`)
+
+// AssetView is a stylesheet or script as it is rendered into the report:
+// either inlined (CSS/JS set) or loaded from the CDN with an SRI hash.
+type AssetView struct {
+	URL       string
+	Integrity string
+	CSS       template.CSS
+	JS        template.JS
+}
+
+// buildAssetViews returns the stylesheets and scripts for the report, inlined
+// from the vendored copies unless cdn is set.
+func buildAssetViews(cdn bool) (styles, scriptViews []AssetView, err error) {
+	for _, a := range stylesheets {
+		if cdn {
+			view, err := cdnAsset(a)
+			if err != nil {
+				return nil, nil, err
+			}
+			styles = append(styles, view)
+			continue
+		}
+		content, err := readAsset(a.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		css := fontURLRegex.ReplaceAllStringFunc(string(content), inlineFont)
+		styles = append(styles, AssetView{CSS: template.CSS(css)}) // #nosec G203: safe – pinned vendored stylesheet
+	}
+	for _, a := range scripts {
+		if cdn {
+			view, err := cdnAsset(a)
+			if err != nil {
+				return nil, nil, err
+			}
+			scriptViews = append(scriptViews, view)
+			continue
+		}
+		content, err := readAsset(a.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		// a literal </script> inside the library would end the inline script early
+		js := strings.ReplaceAll(string(content), "</script", `<\/script`)
+		scriptViews = append(scriptViews, AssetView{JS: template.JS(js)}) // #nosec G203: safe – pinned vendored script
+	}
+	return styles, scriptViews, nil
+}
+
+// cdnAsset references a from the CDN with its pinned SRI hash, or one computed
+// from the vendored copy. It never references an asset without a hash.
+func cdnAsset(a webAsset) (AssetView, error) {
+	if a.Integrity != "" {
+		return AssetView{URL: a.URL, Integrity: a.Integrity}, nil
+	}
+	content, err := readAsset(a.File)
+	if err != nil {
+		return AssetView{}, fmt.Errorf("no SRI hash for %s: %w", a.File, err)
+	}
+	return AssetView{URL: a.URL, Integrity: integrity(content)}, nil
+}
+
+func readAsset(name string) ([]byte, error) {
+	content, err := fs.ReadFile(assetFS, name)
+	if err != nil {
+		return nil, fmt.Errorf("read asset %s (run `make assets` to vendor it): %w", name, err)
+	}
+	return content, nil
+}
+
+// inlineFont rewrites a relative font url() to a data URI. References to fonts
+// that were not vendored are left untouched.
+func inlineFont(ref string) string {
+	m := fontURLRegex.FindStringSubmatch(ref)
+	content, err := fs.ReadFile(assetFS, path.Join("fonts", m[1]))
+	if err != nil {
+		return ref
+	}
+	mime := "font/" + strings.TrimPrefix(path.Ext(m[1]), ".")
+	return fmt.Sprintf(`url("data:%s;base64,%s")`, mime, base64.StdEncoding.EncodeToString(content))
+}
+
+// integrity computes the Subresource Integrity value for content.
+func integrity(content []byte) string {
+	sum := sha512.Sum384(content)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,149 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// fakeAssets stands in for the vendored files, which are not checked out in
+// every development tree.
+var fakeAssets = fstest.MapFS{
+	"bootstrap.min.css":           {Data: []byte(".container{width:100%}")},
+	"bootstrap-icons.min.css":     {Data: []byte(`@font-face{src:url("./fonts/bootstrap-icons.woff2?abc") format("woff2"),url("./fonts/bootstrap-icons.woff?abc") format("woff")}`)},
+	"fonts/bootstrap-icons.woff2": {Data: []byte("woff2")},
+	"bootstrap.bundle.min.js":     {Data: []byte("var bootstrap={};")},
+	"echarts.min.js":              {Data: []byte(`var echarts={};var s="</script>";`)},
+}
+
+func TestMain(m *testing.M) {
+	assetFS = fakeAssets
+	os.Exit(m.Run())
+}
+
+func generate(t *testing.T, opts Options) string {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "out.html")
+	if err := GenerateReportWithOptions(parser.ReportData{}, out, "t", "", "input.top", "abc123", "", opts); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	return string(b)
+}
+
+func TestGenerateReport_InlinesAssets(t *testing.T) {
+	html := generate(t, Options{})
+	if strings.Contains(html, "cdn.jsdelivr.net") {
+		t.Error("self-contained report references the CDN")
+	}
+	for _, want := range []string{".container{width:100%}", "var bootstrap={};", "var echarts={};"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("inlined asset %q not found", want)
+		}
+	}
+	if strings.Contains(html, `"</script>"`) {
+		t.Error("closing script tag inside inlined library was not escaped")
+	}
+	if !strings.Contains(html, `url("data:font/woff2;base64,d29mZjI=")`) {
+		t.Error("vendored font not inlined as data URI")
+	}
+	if !strings.Contains(html, `url("./fonts/bootstrap-icons.woff?abc")`) {
+		t.Error("reference to missing font should be left untouched")
+	}
+}
+
+func TestGenerateReport_CDNWithIntegrity(t *testing.T) {
+	html := generate(t, Options{CDN: true})
+	if !strings.Contains(html, `src="https://cdn.jsdelivr.net/npm/echarts@5.5.0/dist/echarts.min.js"`) {
+		t.Error("pinned echarts CDN URL not found")
+	}
+	want := `integrity="` + integrity(fakeAssets["echarts.min.js"].Data) + `"`
+	if !strings.Contains(html, want) {
+		t.Errorf("SRI hash not found; want %s", want)
+	}
+	if strings.Contains(html, "var echarts={};") {
+		t.Error("CDN report should not inline assets")
+	}
+}
+
+func TestGenerateReport_CDNPinnedIntegrity(t *testing.T) {
+	html := generate(t, Options{CDN: true})
+	// the pinned hash wins over one computed from the (fake) vendored file
+	want := `integrity="` + stylesheets[0].Integrity + `"`
+	if !strings.Contains(html, want) {
+		t.Errorf("pinned SRI hash not found; want %s", want)
+	}
+}
+
+func TestGenerateReport_MissingAsset(t *testing.T) {
+	assetFS = fstest.MapFS{}
+	defer func() { assetFS = fakeAssets }()
+	out := filepath.Join(t.TempDir(), "out.html")
+	err := GenerateReport(parser.ReportData{}, out, "t", "", "input.top", "abc123", "")
+	if err == nil || !strings.Contains(err.Error(), "make assets") {
+		t.Errorf("expected missing asset error, got %v", err)
+	}
+}
+
+func TestGenerateReport_CDNRequiresIntegrity(t *testing.T) {
+	// without a vendored copy only the pinned hashes are known
+	assetFS = fstest.MapFS{}
+	defer func() { assetFS = fakeAssets }()
+	for _, a := range append(append([]webAsset{}, stylesheets...), scripts...) {
+		view, err := cdnAsset(a)
+		if a.Integrity != "" {
+			if err != nil || view.Integrity != a.Integrity {
+				t.Errorf("%s: expected the pinned hash, got %+v, %v", a.File, view, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "no SRI hash") {
+			t.Errorf("%s: expected an error instead of a tag without SRI, got %+v", a.File, view)
+		}
+	}
+}
+
+// TestEmbeddedAssets renders against the assets actually embedded in the
+// binary: the report is self-contained when they were vendored with
+// `make assets` and generation fails otherwise.
+func TestEmbeddedAssets(t *testing.T) {
+	assetFS = mustSub(embeddedAssets, "assets")
+	defer func() { assetFS = fakeAssets }()
+	var missing bool
+	for _, a := range append(append([]webAsset{}, stylesheets...), scripts...) {
+		content, err := readAsset(a.File)
+		if err != nil {
+			missing = true
+			continue
+		}
+		if a.Integrity != "" && integrity(content) != a.Integrity {
+			t.Errorf("vendored %s does not match its pinned CDN hash %s", a.File, a.Integrity)
+		}
+	}
+
+	out := filepath.Join(t.TempDir(), "out.html")
+	err := GenerateReport(parser.ReportData{}, out, "t", "", "input.top", "abc123", "")
+	if missing {
+		if err == nil || !strings.Contains(err.Error(), "make assets") {
+			t.Errorf("expected generation to fail without vendored assets, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if strings.Contains(string(b), "cdn.jsdelivr.net") {
+		t.Error("report from the embedded assets references the CDN")
+	}
+}
@@ -0,0 +1,41 @@
+package reporter
+
+import "github.com/rsvihladremio/threaded-top-reporter/analysis"
+
+// hostCPUSeries names the host CPU overlay on the attribution chart.
+const hostCPUSeries = "Host user + system"
+
+// attributionSeries converts an attribution into ECharts series: the bands
+// and "other" stacked, with the host CPU drawn over them as a dashed line.
+func attributionSeries(a analysis.Attribution) []map[string]interface{} {
+	band := func(name string, cpu []float64) map[string]interface{} {
+		return map[string]interface{}{
+			"name":      name,
+			"type":      "line",
+			"stack":     "attribution",
+			"areaStyle": map[string]interface{}{},
+			"symbol":    "none",
+			"data":      cpu,
+		}
+	}
+	series := []map[string]interface{}{}
+	for _, b := range a.Bands {
+		series = append(series, band(b.Name, b.CPU))
+	}
+	if a.Other != nil {
+		other := band("other", a.Other)
+		other["itemStyle"] = map[string]interface{}{"color": "#adb5bd"}
+		series = append(series, other)
+	}
+	if a.Host != nil {
+		series = append(series, map[string]interface{}{
+			"name":      hostCPUSeries,
+			"type":      "line",
+			"symbol":    "none",
+			"data":      a.Host,
+			"itemStyle": map[string]interface{}{"color": "#212529"},
+			"lineStyle": map[string]interface{}{"type": "dashed", "width": 2},
+		})
+	}
+	return series
+}
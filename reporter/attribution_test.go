@@ -0,0 +1,28 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestAttributionSeries(t *testing.T) {
+	series := attributionSeries(analysis.Attribution{
+		Bands: []analysis.AttributionBand{{Name: "worker-1 (1)", CPU: []float64{80}}},
+		Other: []float64{20},
+		Host:  []float64{150},
+	})
+	if len(series) != 3 {
+		t.Fatalf("expected a band, other and the host line, got %+v", series)
+	}
+	if series[0]["stack"] != "attribution" || series[1]["name"] != "other" || series[1]["stack"] != "attribution" {
+		t.Errorf("expected the band and other to stack, got %+v", series[:2])
+	}
+	if series[2]["name"] != hostCPUSeries || series[2]["stack"] != nil {
+		t.Errorf("expected the host line not to stack, got %+v", series[2])
+	}
+
+	if series := attributionSeries(analysis.Attribution{}); series == nil || len(series) != 0 {
+		t.Errorf("expected an empty series list, got %+v", series)
+	}
+}
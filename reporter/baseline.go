@@ -0,0 +1,79 @@
+package reporter
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// DeviationView is one row of the baseline deviation table.
+type DeviationView struct {
+	Kind          string
+	Name          string
+	BaselineRange string
+	BaselineMean  float64
+	Mean          float64
+	Score         float64
+	Outside       float64
+	New           bool
+	Class         string // row highlight for large deviations
+}
+
+// BaselineBand is the baseline's normal range drawn behind a chart.
+type BaselineBand struct {
+	Chart string  `json:"chart"`
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Label string  `json:"label"`
+}
+
+// baselineCharts maps system metrics to the chart their band is drawn on.
+// Total CPU has no single line to compare against, so it only gets a row.
+var baselineCharts = map[string]string{
+	analysis.MetricIOWait:   "totalCpuChart",
+	analysis.MetricSteal:    "totalCpuChart",
+	analysis.MetricLoad1:    "loadAvgChart",
+	analysis.MetricMemUsed:  "memoryUsageChart",
+	analysis.MetricSwapUsed: "memoryUsageChart",
+	"Threads":               "threadStatesChart",
+}
+
+// buildBaselineViews converts deviations for display and collects the chart
+// bands of the system metrics.
+func buildBaselineViews(devs []analysis.Deviation) ([]DeviationView, []BaselineBand) {
+	var views []DeviationView
+	var bands []BaselineBand
+	for _, d := range devs {
+		v := DeviationView{
+			Kind:          d.Kind,
+			Name:          d.Name,
+			BaselineRange: fmt.Sprintf("%.1f – %.1f", d.Baseline.P5, d.Baseline.P95),
+			BaselineMean:  d.Baseline.Mean,
+			Mean:          d.Mean,
+			Score:         d.Score,
+			Outside:       d.Outside,
+			New:           d.New,
+		}
+		switch score := math.Abs(d.Score); {
+		case score >= 3:
+			v.Class = "table-danger"
+		case score >= 2:
+			v.Class = "table-warning"
+		}
+		if d.New {
+			v.BaselineRange = "–"
+		}
+		views = append(views, v)
+
+		if chart, ok := baselineCharts[d.Name]; ok && d.Kind == analysis.DeviationMetric {
+			bands = append(bands, BaselineBand{
+				Chart: chart,
+				Low:   d.Baseline.P5,
+				High:  d.Baseline.P95,
+				Label: "baseline " + d.Name,
+			})
+		}
+	}
+	return views, bands
+}
@@ -0,0 +1,28 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildBaselineViews(t *testing.T) {
+	devs := []analysis.Deviation{
+		{Kind: analysis.DeviationMetric, Name: analysis.MetricIOWait, Baseline: analysis.Distribution{P5: 1, P95: 3}, Mean: 30, Score: 12},
+		{Kind: analysis.DeviationPool, Name: "new", Mean: 40, Score: 2.5, New: true},
+		{Kind: analysis.DeviationMetric, Name: analysis.MetricTotalCPU, Baseline: analysis.Distribution{P5: 10, P95: 20}, Mean: 15},
+	}
+	views, bands := buildBaselineViews(devs)
+	if views[0].Class != "table-danger" || views[0].BaselineRange != "1.0 – 3.0" {
+		t.Errorf("unexpected iowait row: %+v", views[0])
+	}
+	if views[1].Class != "table-warning" || views[1].BaselineRange != "–" {
+		t.Errorf("unexpected new pool row: %+v", views[1])
+	}
+	if views[2].Class != "" {
+		t.Errorf("expected no highlight for a normal metric: %+v", views[2])
+	}
+	if len(bands) != 1 || bands[0] != (BaselineBand{Chart: "totalCpuChart", Low: 1, High: 3, Label: "baseline IOWait"}) {
+		t.Errorf("unexpected bands: %+v", bands)
+	}
+}
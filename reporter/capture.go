@@ -0,0 +1,59 @@
+package reporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+)
+
+// captureElementRegex finds the embedded capture written by the base template.
+var captureElementRegex = regexp.MustCompile(`<script type="application/gzip" id="ttoprep-capture" data-filename="([^"]*)" data-sha256="([0-9a-f]*)">([A-Za-z0-9_=-]*)</script>`)
+
+// encodeCapture gzips and base64-encodes the raw input for embedding in a
+// report. The URL-safe alphabet is used because html/template escapes '+'.
+func encodeCapture(raw []byte) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return "", fmt.Errorf("compress capture: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("compress capture: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// ExtractCapture recovers the original capture embedded in a report. It returns
+// the recorded file name and the decompressed content, and fails when the
+// content does not match the SHA-256 recorded in the report.
+func ExtractCapture(report []byte) (fileName string, data []byte, err error) {
+	m := captureElementRegex.FindSubmatch(report)
+	if m == nil {
+		return "", nil, fmt.Errorf("report does not contain an embedded capture")
+	}
+	fileName = html.UnescapeString(string(m[1]))
+	wantHash := string(m[2])
+
+	compressed, err := base64.URLEncoding.DecodeString(string(m[3]))
+	if err != nil {
+		return "", nil, fmt.Errorf("decode capture: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", nil, fmt.Errorf("decompress capture: %w", err)
+	}
+	data, err = io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("decompress capture: %w", err)
+	}
+
+	if gotHash := fmt.Sprintf("%x", sha256.Sum256(data)); gotHash != wantHash {
+		return "", nil, fmt.Errorf("capture hash mismatch: report records %s, content hashes to %s", wantHash, gotHash)
+	}
+	return fileName, data, nil
+}
@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func TestEmbedAndExtractCapture(t *testing.T) {
+	raw := []byte(strings.Repeat("top - 12:00:00 up 1:00,  0 users,  load average: 0.00, 0.00, 0.00\n", 50))
+	for i := 0; i < 256; i++ {
+		raw = append(raw, byte(i)) // make sure every base64 character shows up
+	}
+	hash := fmt.Sprintf("%x", sha256.Sum256(raw))
+	out := filepath.Join(t.TempDir(), "out.html")
+	opts := Options{Capture: raw}
+	if err := GenerateReportWithOptions(parser.ReportData{}, out, "t", "", "a&b.txt", hash, "", opts); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	report, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(report), "Download original capture") {
+		t.Error("download button not rendered")
+	}
+
+	name, data, err := ExtractCapture(report)
+	if err != nil {
+		t.Fatalf("ExtractCapture failed: %v", err)
+	}
+	if name != "a&b.txt" {
+		t.Errorf("file name = %q, want %q", name, "a&b.txt")
+	}
+	if string(data) != string(raw) {
+		t.Errorf("extracted capture = %q, want %q", data, raw)
+	}
+
+	// a report whose recorded hash does not match must be rejected
+	tampered := strings.Replace(string(report), `data-sha256="`+hash, `data-sha256="`+strings.Repeat("0", 64), 1)
+	if _, _, err := ExtractCapture([]byte(tampered)); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Errorf("expected hash mismatch, got %v", err)
+	}
+}
+
+func TestExtractCapture_NotEmbedded(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	if err := GenerateReport(parser.ReportData{}, out, "t", "", "in.txt", "abc123", ""); err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+	report, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if strings.Contains(string(report), "Download original capture") {
+		t.Error("download button rendered without an embedded capture")
+	}
+	if _, _, err := ExtractCapture(report); err == nil {
+		t.Error("expected error for report without capture")
+	}
+}
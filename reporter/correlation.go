@@ -0,0 +1,66 @@
+package reporter
+
+import (
+	"math"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+const (
+	// maxCorrelationRows limits the table to the strongest correlations.
+	maxCorrelationRows = 20
+	// minCorrelationStrength hides weak correlations from the table.
+	minCorrelationStrength = 0.5
+	// maxHeatmapSubjects is how many threads and pools get a heatmap row.
+	maxHeatmapSubjects = 15
+)
+
+// correlationHeatmap is the Pearson coefficient of each subject (row) and
+// system metric (column), as [column, row, value] cells.
+type correlationHeatmap struct {
+	Subjects []string     `json:"subjects"`
+	Metrics  []string     `json:"metrics"`
+	Cells    [][3]float64 `json:"cells"`
+}
+
+// buildCorrelationViews picks the table rows and heatmap from correlations
+// ordered strongest first.
+func buildCorrelationViews(corr []analysis.Correlation) ([]analysis.Correlation, correlationHeatmap) {
+	var rows []analysis.Correlation
+	for _, c := range corr {
+		if len(rows) == maxCorrelationRows || c.Strength() < minCorrelationStrength {
+			break
+		}
+		rows = append(rows, c)
+	}
+
+	heatmap := correlationHeatmap{Subjects: []string{}, Cells: [][3]float64{}}
+	for _, m := range analysis.CorrelationMetrics {
+		heatmap.Metrics = append(heatmap.Metrics, m.Name)
+	}
+	label := func(c analysis.Correlation) string {
+		if c.Kind == analysis.CorrelationPool {
+			return "pool " + c.Subject
+		}
+		return c.Subject
+	}
+	row := make(map[string]int)
+	for _, c := range corr {
+		if _, ok := row[label(c)]; !ok && len(row) < maxHeatmapSubjects {
+			row[label(c)] = len(heatmap.Subjects)
+			heatmap.Subjects = append(heatmap.Subjects, label(c))
+		}
+	}
+	for _, c := range corr {
+		y, ok := row[label(c)]
+		if !ok {
+			continue
+		}
+		for x, name := range heatmap.Metrics {
+			if name == c.Metric {
+				heatmap.Cells = append(heatmap.Cells, [3]float64{float64(x), float64(y), math.Round(c.Pearson*100) / 100})
+			}
+		}
+	}
+	return rows, heatmap
+}
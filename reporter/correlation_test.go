@@ -0,0 +1,43 @@
+package reporter
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildCorrelationViews(t *testing.T) {
+	corr := []analysis.Correlation{
+		{Kind: analysis.CorrelationThread, Subject: "writer (1)", Metric: analysis.MetricIOWait, Pearson: 0.956, Spearman: 0.9},
+		{Kind: analysis.CorrelationPool, Subject: "writer", Metric: analysis.MetricIOWait, Pearson: 0.8, Spearman: 0.85},
+		{Kind: analysis.CorrelationThread, Subject: "writer (1)", Metric: analysis.MetricSteal, Pearson: -0.6, Spearman: -0.2},
+		{Kind: analysis.CorrelationThread, Subject: "noise (2)", Metric: analysis.MetricLoad1, Pearson: 0.1, Spearman: 0.2},
+	}
+	rows, heatmap := buildCorrelationViews(corr)
+	if len(rows) != 3 {
+		t.Errorf("expected the weak correlation to be left out of the table, got %+v", rows)
+	}
+	if want := []string{"writer (1)", "pool writer", "noise (2)"}; !reflect.DeepEqual(heatmap.Subjects, want) {
+		t.Errorf("expected heatmap rows %v, got %v", want, heatmap.Subjects)
+	}
+	if heatmap.Metrics[0] != analysis.MetricIOWait || heatmap.Metrics[1] != analysis.MetricSteal || heatmap.Metrics[3] != analysis.MetricLoad1 {
+		t.Errorf("unexpected heatmap columns: %v", heatmap.Metrics)
+	}
+	want := [][3]float64{{0, 0, 0.96}, {0, 1, 0.8}, {1, 0, -0.6}, {3, 2, 0.1}}
+	if !reflect.DeepEqual(heatmap.Cells, want) {
+		t.Errorf("expected cells %v, got %v", want, heatmap.Cells)
+	}
+}
+
+func TestBuildCorrelationViews_Limits(t *testing.T) {
+	var corr []analysis.Correlation
+	for i := 0; i < 40; i++ {
+		corr = append(corr, analysis.Correlation{Subject: fmt.Sprintf("t%d", i), Metric: analysis.MetricIOWait, Pearson: 0.9})
+	}
+	rows, heatmap := buildCorrelationViews(corr)
+	if len(rows) != maxCorrelationRows || len(heatmap.Subjects) != maxHeatmapSubjects || len(heatmap.Cells) != maxHeatmapSubjects {
+		t.Errorf("expected %d rows and %d heatmap subjects, got %d and %d", maxCorrelationRows, maxHeatmapSubjects, len(rows), len(heatmap.Subjects))
+	}
+}
@@ -0,0 +1,263 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// DiffInput is one side of a diff.
+type DiffInput struct {
+	Data     parser.ReportData
+	FileName string
+	FileHash string
+}
+
+// DiffOptions holds optional diff report settings.
+type DiffOptions struct {
+	CDN   bool                 // load Bootstrap and ECharts from the CDN instead of inlining them
+	Pools *analysis.Normalizer // nil uses the built-in normalization only
+	// Top limits the regression and improvement tables, default 20.
+	Top int
+	// MinDelta hides CPU changes smaller than this many %CPU, default 1.
+	MinDelta float64
+}
+
+// DiffSide describes one capture in the diff header.
+type DiffSide struct {
+	FileName      string
+	FileHashShort string
+	Snapshots     int
+	Duration      string
+}
+
+// MetricDiffView is a system metric row of the diff.
+type MetricDiffView struct {
+	Name       string
+	Unit       string
+	Before     analysis.Summary
+	After      analysis.Summary
+	Delta      string
+	DeltaClass string // table-danger for regressions, table-success for improvements
+}
+
+// CPUDiffView is a thread or pool row of the ranked change tables.
+type CPUDiffView struct {
+	Kind   string // "thread" or "pool"
+	Name   string
+	Before float64
+	After  float64
+	Delta  float64
+}
+
+// DiffViewModel is the data behind diff.html.
+type DiffViewModel struct {
+	Title        string
+	AppVersion   string
+	Before       DiffSide
+	After        DiffSide
+	Metrics      []MetricDiffView
+	Regressions  []CPUDiffView
+	Improvements []CPUDiffView
+	ChartsJson   template.JS
+	Stylesheets  []AssetView
+	Scripts      []AssetView
+}
+
+// diffChart is a system metric overlaid for both captures on a shared axis of
+// seconds since each capture started.
+type diffChart struct {
+	ID     string       `json:"id"`
+	Title  string       `json:"title"`
+	Unit   string       `json:"unit"`
+	Before [][2]float64 `json:"before"`
+	After  [][2]float64 `json:"after"`
+}
+
+func (o DiffOptions) withDefaults() DiffOptions {
+	if o.Top <= 0 {
+		o.Top = 20
+	}
+	if o.MinDelta <= 0 {
+		o.MinDelta = 1
+	}
+	return o
+}
+
+// buildDiffView compares both captures and lays out the report.
+func buildDiffView(before, after DiffInput, opts DiffOptions) DiffViewModel {
+	opts = opts.withDefaults()
+	cmp := analysis.Compare(before.Data, after.Data, opts.Pools)
+	vm := DiffViewModel{Before: diffSide(before), After: diffSide(after)}
+
+	for _, m := range cmp.Metrics {
+		delta := m.Delta()
+		view := MetricDiffView{Name: m.Name, Unit: m.Unit, Before: m.Before, After: m.After, Delta: fmt.Sprintf("%+.1f", delta)}
+		if m.Before.Mean != 0 {
+			pct := delta / math.Abs(m.Before.Mean) * 100
+			view.Delta += fmt.Sprintf(" (%+.0f%%)", pct)
+			switch {
+			case pct >= 5:
+				view.DeltaClass = "table-danger"
+			case pct <= -5:
+				view.DeltaClass = "table-success"
+			}
+		}
+		vm.Metrics = append(vm.Metrics, view)
+	}
+
+	var changes []CPUDiffView
+	for _, group := range []struct {
+		kind string
+		rows []analysis.CPUComparison
+	}{{"thread", cmp.Threads}, {"pool", cmp.Pools}} {
+		for _, c := range group.rows {
+			if math.Abs(c.Delta()) < opts.MinDelta {
+				continue
+			}
+			changes = append(changes, CPUDiffView{Kind: group.kind, Name: c.Name, Before: c.Before, After: c.After, Delta: c.Delta()})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return math.Abs(changes[i].Delta) > math.Abs(changes[j].Delta)
+	})
+	for _, c := range changes {
+		if c.Delta > 0 && len(vm.Regressions) < opts.Top {
+			vm.Regressions = append(vm.Regressions, c)
+		}
+		if c.Delta < 0 && len(vm.Improvements) < opts.Top {
+			vm.Improvements = append(vm.Improvements, c)
+		}
+	}
+	return vm
+}
+
+func diffSide(in DiffInput) DiffSide {
+	side := DiffSide{FileName: in.FileName, FileHashShort: in.FileHash, Snapshots: len(in.Data.Snapshots)}
+	if len(side.FileHashShort) > 6 {
+		side.FileHashShort = side.FileHashShort[:6]
+	}
+	if offsets := in.Data.Offsets(); len(offsets) > 0 {
+		side.Duration = offsets[len(offsets)-1].Round(time.Second).String()
+	}
+	return side
+}
+
+// buildDiffCharts aligns both captures by time since their first snapshot.
+func buildDiffCharts(before, after parser.ReportData) []diffChart {
+	aligned := func(data parser.ReportData, values []float64) [][2]float64 {
+		points := make([][2]float64, len(values))
+		for i, o := range data.Offsets() {
+			points[i] = [2]float64{o.Seconds(), values[i]}
+		}
+		return points
+	}
+	var charts []diffChart
+	for i, m := range analysis.SystemMetrics {
+		charts = append(charts, diffChart{
+			ID:     fmt.Sprintf("diffChart%d", i),
+			Title:  m.Name,
+			Unit:   m.Unit,
+			Before: aligned(before, m.Values(before)),
+			After:  aligned(after, m.Values(after)),
+		})
+	}
+	return charts
+}
+
+// GenerateDiffReport writes an HTML report comparing two captures to
+// outputPath.
+func GenerateDiffReport(before, after DiffInput, outputPath, title, appVersion string, opts DiffOptions) (err error) {
+	cleanOutput := filepath.Clean(outputPath)
+	if strings.Contains(cleanOutput, "..") {
+		return fmt.Errorf("invalid output path: %s", outputPath)
+	}
+	outputPath = cleanOutput
+
+	vm := buildDiffView(before, after, opts)
+	vm.Title = title
+	vm.AppVersion = appVersion
+	chartsJson, err := json.Marshal(buildDiffCharts(before.Data, after.Data))
+	if err != nil {
+		return fmt.Errorf("marshal diff charts: %w", err)
+	}
+	vm.ChartsJson = template.JS(string(chartsJson)) // #nosec G203: safe – marshaled JSON only contains numbers and metric names
+	if vm.Stylesheets, vm.Scripts, err = buildAssetViews(opts.CDN); err != nil {
+		return fmt.Errorf("load web assets: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0750); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+	var f *os.File
+	f, err = os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close file: %w", closeErr)
+		}
+	}()
+
+	if err = tmpl.ExecuteTemplate(f, "diff.html", vm); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+	return
+}
+
+// WriteDiffMarkdown writes the comparison as markdown tables for pasting into
+// tickets.
+func WriteDiffMarkdown(w io.Writer, before, after DiffInput, opts DiffOptions) error {
+	vm := buildDiffView(before, after, opts)
+	var b strings.Builder
+	fmt.Fprintf(&b, "## ttoprep diff: %s → %s\n\n", mdEscape(vm.Before.FileName), mdEscape(vm.After.FileName))
+	fmt.Fprintf(&b, "Before: %d snapshots over %s. After: %d snapshots over %s.\n\n",
+		vm.Before.Snapshots, vm.Before.Duration, vm.After.Snapshots, vm.After.Duration)
+
+	b.WriteString("| Metric | Before mean | After mean | Δ mean | Before p95 | After p95 |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|\n")
+	for _, m := range vm.Metrics {
+		name := m.Name
+		if m.Unit != "" {
+			name += " (" + m.Unit + ")"
+		}
+		fmt.Fprintf(&b, "| %s | %.1f | %.1f | %s | %.1f | %.1f |\n", name, m.Before.Mean, m.After.Mean, m.Delta, m.Before.P95, m.After.P95)
+	}
+
+	for _, section := range []struct {
+		title string
+		rows  []CPUDiffView
+	}{{"Biggest regressions", vm.Regressions}, {"Biggest improvements", vm.Improvements}} {
+		fmt.Fprintf(&b, "\n### %s\n\n", section.title)
+		if len(section.rows) == 0 {
+			b.WriteString("None.\n")
+			continue
+		}
+		b.WriteString("| | Name | Before %CPU | After %CPU | Δ %CPU |\n")
+		b.WriteString("|---|---|---:|---:|---:|\n")
+		for _, c := range section.rows {
+			fmt.Fprintf(&b, "| %s | %s | %.1f | %.1f | %+.1f |\n", c.Kind, mdEscape(c.Name), c.Before, c.After, c.Delta)
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("write markdown: %w", err)
+	}
+	return nil
+}
+
+// mdEscape keeps thread names from breaking markdown tables.
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
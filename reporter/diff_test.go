@@ -0,0 +1,91 @@
+package reporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func diffInputs() (DiffInput, DiffInput) {
+	capture := func(user, planner, gc, noise float64) parser.ReportData {
+		var snaps []parser.Snapshot
+		for i := 0; i < 3; i++ {
+			snaps = append(snaps, parser.Snapshot{
+				Time:     time.Date(0, 1, 1, 12, 0, i*5, 0, time.UTC),
+				Metadata: parser.Metadata{CPUUser: user, MemUsed: 1000},
+				Processes: []parser.ProcessData{
+					{PID: 1, Command: "planner", CPU: planner},
+					{PID: 2, Command: "GC Thread#0", CPU: gc},
+					{PID: 3, Command: "noise|pipe", CPU: noise},
+				},
+			})
+		}
+		return parser.ReportData{Snapshots: snaps}
+	}
+	return DiffInput{Data: capture(20, 10, 30, 0.2), FileName: "before.txt", FileHash: "aaaaaaaaaa"},
+		DiffInput{Data: capture(40, 60, 5, 0.5), FileName: "after.txt", FileHash: "bbbbbbbbbb"}
+}
+
+func TestBuildDiffView(t *testing.T) {
+	before, after := diffInputs()
+	vm := buildDiffView(before, after, DiffOptions{})
+	if vm.Before.Duration != "10s" || vm.Before.FileHashShort != "aaaaaa" {
+		t.Errorf("unexpected before side: %+v", vm.Before)
+	}
+	if vm.Metrics[0].Delta != "+20.0 (+100%)" || vm.Metrics[0].DeltaClass != "table-danger" {
+		t.Errorf("unexpected total CPU row: %+v", vm.Metrics[0])
+	}
+	if len(vm.Regressions) != 2 || vm.Regressions[0].Name != "planner" || vm.Regressions[0].Delta != 50 {
+		t.Errorf("unexpected regressions: %+v", vm.Regressions)
+	}
+	if len(vm.Improvements) != 2 || vm.Improvements[0].Name != "GC Thread#0" {
+		t.Errorf("unexpected improvements: %+v", vm.Improvements)
+	}
+	for _, c := range append(vm.Regressions, vm.Improvements...) {
+		if strings.HasPrefix(c.Name, "noise") {
+			t.Errorf("changes below MinDelta should be hidden: %+v", c)
+		}
+	}
+}
+
+func TestWriteDiffMarkdown(t *testing.T) {
+	before, after := diffInputs()
+	var b strings.Builder
+	if err := WriteDiffMarkdown(&b, before, after, DiffOptions{MinDelta: 0.01}); err != nil {
+		t.Fatal(err)
+	}
+	md := b.String()
+	for _, want := range []string{
+		"## ttoprep diff: before.txt → after.txt",
+		"| Total CPU (%) | 20.0 | 40.0 | +20.0 (+100%) |",
+		"| thread | planner | 10.0 | 60.0 | +50.0 |",
+		"### Biggest improvements",
+		`noise\|pipe`,
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestGenerateDiffReport(t *testing.T) {
+	before, after := diffInputs()
+	out := filepath.Join(t.TempDir(), "diff.html")
+	if err := GenerateDiffReport(before, after, out, "Upgrade", "test", DiffOptions{}); err != nil {
+		t.Fatalf("GenerateDiffReport failed: %v", err)
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(content)
+	for _, want := range []string{"<title>Upgrade</title>", "before.txt", `id="metricDiffTable"`, "<td>planner</td>", `"before":[[0,20],[5,20],[10,20]]`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("diff report missing %q", want)
+		}
+	}
+}
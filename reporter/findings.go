@@ -0,0 +1,44 @@
+package reporter
+
+import (
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// FindingView is a finding as listed at the top of the report.
+type FindingView struct {
+	Severity    string
+	Class       string // Bootstrap alert class for the severity
+	Icon        string // Bootstrap icon name for the severity
+	Title       string
+	Explanation string
+	TimeRange   string
+	Threads     []string
+}
+
+// buildFindingViews converts findings for display, using times as the x axis
+// labels.
+func buildFindingViews(findings []analysis.Finding, times []string) []FindingView {
+	var views []FindingView
+	for _, f := range findings {
+		view := FindingView{
+			Severity:    f.Severity.String(),
+			Title:       f.Title,
+			Explanation: f.Explanation,
+			TimeRange:   times[f.Start],
+			Threads:     f.Threads,
+		}
+		if f.End != f.Start {
+			view.TimeRange += " – " + times[f.End]
+		}
+		switch f.Severity {
+		case analysis.SeverityCritical:
+			view.Class, view.Icon = "alert-danger", "exclamation-octagon"
+		case analysis.SeverityWarning:
+			view.Class, view.Icon = "alert-warning", "exclamation-triangle"
+		default:
+			view.Class, view.Icon = "alert-info", "info-circle"
+		}
+		views = append(views, view)
+	}
+	return views
+}
@@ -0,0 +1,29 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildFindingViews(t *testing.T) {
+	times := []string{"12:00:00", "12:00:10", "12:00:20"}
+	views := buildFindingViews([]analysis.Finding{
+		{Severity: analysis.SeverityCritical, Title: "High iowait", Start: 0, End: 2, Threads: []string{"writer (1)"}},
+		{Severity: analysis.SeverityWarning, Title: "Zombie threads", Start: 1, End: 1},
+		{Severity: analysis.SeverityInfo, Title: "note", Start: 2, End: 2},
+	}, times)
+	if len(views) != 3 {
+		t.Fatalf("expected 3 views, got %d", len(views))
+	}
+	if v := views[0]; v.Severity != "critical" || v.Class != "alert-danger" || v.TimeRange != "12:00:00 – 12:00:20" || !reflect.DeepEqual(v.Threads, []string{"writer (1)"}) {
+		t.Errorf("unexpected critical view: %+v", v)
+	}
+	if v := views[1]; v.Class != "alert-warning" || v.TimeRange != "12:00:10" {
+		t.Errorf("unexpected warning view: %+v", v)
+	}
+	if v := views[2]; v.Class != "alert-info" || v.Icon != "info-circle" {
+		t.Errorf("unexpected info view: %+v", v)
+	}
+}
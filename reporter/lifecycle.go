@@ -0,0 +1,57 @@
+package reporter
+
+import (
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// maxLifecyclePools is how many pools get their own series in the lifecycle
+// timeline; the rest are combined.
+const maxLifecyclePools = 8
+
+// lifecycleSeries builds stacked bar series of thread appearances (positive)
+// and disappearances (negative) per snapshot, one pair per pool. Both series
+// of a pool share its name so the legend toggles them together.
+func lifecycleSeries(lc analysis.Lifecycle, snapshots int) []map[string]interface{} {
+	pools := make(map[string]bool)
+	for i, c := range lc.Churn {
+		if i < maxLifecyclePools {
+			pools[c.Pool] = true
+		}
+	}
+	name := func(pool string) string {
+		if pools[pool] {
+			return pool
+		}
+		return "other pools"
+	}
+
+	var order []string
+	counts := make(map[string][2][]int)
+	for _, e := range lc.Events {
+		n := name(e.Pool)
+		c, ok := counts[n]
+		if !ok {
+			order = append(order, n)
+			c = [2][]int{make([]int, snapshots), make([]int, snapshots)}
+			counts[n] = c
+		}
+		if e.Kind == analysis.EventAppeared {
+			c[0][e.Index]++
+		} else {
+			c[1][e.Index]--
+		}
+	}
+
+	var series []map[string]interface{}
+	for _, n := range order {
+		for i, stack := range []string{"appeared", "disappeared"} {
+			series = append(series, map[string]interface{}{
+				"name":  n,
+				"type":  "bar",
+				"stack": stack,
+				"data":  counts[n][i],
+			})
+		}
+	}
+	return series
+}
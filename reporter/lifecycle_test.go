@@ -0,0 +1,34 @@
+package reporter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestLifecycleSeries(t *testing.T) {
+	lc := analysis.Lifecycle{
+		Events: []analysis.LifecycleEvent{
+			{Index: 1, Pool: "worker", Kind: analysis.EventAppeared},
+			{Index: 1, Pool: "worker", Kind: analysis.EventAppeared},
+			{Index: 2, Pool: "worker", Kind: analysis.EventDisappeared},
+		},
+		Churn: []analysis.PoolChurn{{Pool: "worker", Appeared: 2, Disappeared: 1}},
+	}
+	for i := 0; i < maxLifecyclePools; i++ {
+		lc.Churn = append(lc.Churn, analysis.PoolChurn{Pool: fmt.Sprintf("pool%d", i)})
+	}
+	lc.Events = append(lc.Events, analysis.LifecycleEvent{Index: 0, Pool: "pool7", Kind: analysis.EventDisappeared})
+
+	series := lifecycleSeries(lc, 3)
+	if len(series) != 4 {
+		t.Fatalf("expected appeared/disappeared series for two pools, got %+v", series)
+	}
+	if series[0]["name"] != "worker" || fmt.Sprint(series[0]["data"]) != "[0 2 0]" || fmt.Sprint(series[1]["data"]) != "[0 0 -1]" {
+		t.Errorf("unexpected worker series: %+v %+v", series[0], series[1])
+	}
+	if series[2]["name"] != "other pools" || fmt.Sprint(series[3]["data"]) != "[-1 0 0]" {
+		t.Errorf("expected pools beyond the limit to be combined, got %+v %+v", series[2], series[3])
+	}
+}
@@ -0,0 +1,104 @@
+package reporter
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// MemoryTrendView is one memory trend as listed in the report.
+type MemoryTrendView struct {
+	Metric      string
+	Change      string // first and last value
+	Rate        string
+	R2          float64
+	Significant bool
+	Exhaustion  string // projected time until the limit is reached, if any
+}
+
+// MemoryView summarizes the memory trends, with Finding describing the
+// earliest projected exhaustion.
+type MemoryView struct {
+	Trends  []MemoryTrendView
+	Finding string
+}
+
+// buildMemoryView describes the memory trends and returns the extra chart
+// series: the available memory and process RES when captured and a dashed
+// trend line for every significant trend.
+func buildMemoryView(trends []analysis.MemoryTrend, avail, res []float64) (MemoryView, []map[string]interface{}) {
+	var view MemoryView
+	var series []map[string]interface{}
+	for _, t := range trends {
+		switch t.Metric {
+		case analysis.MetricMemAvail:
+			series = append(series, map[string]interface{}{
+				"name": "Available",
+				"type": "line",
+				"data": avail,
+			})
+		case analysis.MetricProcessRES:
+			series = append(series, map[string]interface{}{
+				"name": "Process RES",
+				"type": "line",
+				"data": res,
+			})
+		}
+	}
+
+	var earliest *analysis.MemoryTrend
+	for i, t := range trends {
+		v := MemoryTrendView{
+			Metric:      t.Metric,
+			Change:      fmt.Sprintf("%.0f → %.0f MiB", t.First, t.Last),
+			Rate:        fmt.Sprintf("%+.1f MiB/min", t.SlopePerMinute),
+			R2:          t.R2,
+			Significant: t.Significant,
+		}
+		if t.TimeToExhaustion > 0 {
+			v.Exhaustion = formatProjection(t.TimeToExhaustion)
+			if earliest == nil || t.TimeToExhaustion < earliest.TimeToExhaustion {
+				earliest = &trends[i]
+			}
+		}
+		view.Trends = append(view.Trends, v)
+
+		if t.Significant {
+			fit := make([]float64, len(t.Fit))
+			for j, f := range t.Fit {
+				fit[j] = math.Round(f*10) / 10
+			}
+			series = append(series, map[string]interface{}{
+				"name":      t.Metric + " trend",
+				"type":      "line",
+				"symbol":    "none",
+				"lineStyle": map[string]interface{}{"type": "dashed"},
+				"data":      fit,
+			})
+		}
+	}
+
+	if earliest != nil {
+		what := "memory"
+		if earliest.Metric == analysis.MetricSwapUsed {
+			what = "swap"
+		}
+		view.Finding = fmt.Sprintf("%s is changing by %+.1f MiB/min (R² %.2f); at this rate %s runs out in about %s, an OOM risk.",
+			earliest.Metric, earliest.SlopePerMinute, earliest.R2, what, formatProjection(earliest.TimeToExhaustion))
+	}
+	return view, series
+}
+
+// formatProjection rounds a projected duration to a readable precision.
+func formatProjection(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%.0f days", d.Hours()/24)
+	case d >= time.Hour:
+		return d.Round(time.Minute).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}
@@ -0,0 +1,42 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildMemoryView(t *testing.T) {
+	trends := []analysis.MemoryTrend{
+		{Metric: analysis.MetricMemUsed, First: 4000, Last: 5000, SlopePerMinute: 100, R2: 0.99, Significant: true, Fit: []float64{4000, 5000}, TimeToExhaustion: 110 * time.Minute},
+		{Metric: analysis.MetricMemAvail, First: 12000, Last: 11000, SlopePerMinute: -100, R2: 0.99, Fit: []float64{12000, 11000}},
+		{Metric: analysis.MetricSwapUsed, First: 10, Last: 11, R2: 0.1, Fit: []float64{10, 11}},
+		{Metric: analysis.MetricProcessRES, First: 1000, Last: 2000, SlopePerMinute: 100, R2: 1, Significant: true, Fit: []float64{1000, 2000}, TimeToExhaustion: 140 * time.Minute},
+	}
+	view, series := buildMemoryView(trends, []float64{12000, 11000}, []float64{1000, 2000})
+
+	if !strings.HasPrefix(view.Finding, "Mem used is changing by +100.0 MiB/min") || !strings.Contains(view.Finding, "about 1h50m0s") {
+		t.Errorf("unexpected finding: %s", view.Finding)
+	}
+	if view.Trends[0].Exhaustion != "1h50m0s" || view.Trends[2].Exhaustion != "" {
+		t.Errorf("unexpected trend views: %+v", view.Trends)
+	}
+	var names []string
+	for _, s := range series {
+		names = append(names, s["name"].(string))
+	}
+	if strings.Join(names, ",") != "Available,Process RES,Mem used trend,Process RES trend" {
+		t.Errorf("unexpected chart series: %v", names)
+	}
+}
+
+func TestFormatProjection(t *testing.T) {
+	if got := formatProjection(72 * time.Hour); got != "3 days" {
+		t.Errorf("got %s", got)
+	}
+	if got := formatProjection(90*time.Second + 300*time.Millisecond); got != "1m30s" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,63 @@
+package reporter
+
+import (
+	"fmt"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// PeriodView is a recurring pattern as listed in the report.
+type PeriodView struct {
+	Kind        string
+	Series      string
+	Period      string
+	Strength    float64
+	Recurrences int
+	Threads     []string
+}
+
+// periodMarker marks the recurrences of a period on a chart.
+type periodMarker struct {
+	Chart string `json:"chart"`
+	Label string `json:"label"`
+	Peaks []int  `json:"peaks"`
+}
+
+// periodicityChart is the chart each series is drawn on.
+func periodicityChart(p analysis.Periodicity) string {
+	switch {
+	case p.Kind == analysis.PeriodicityPool:
+		return "poolCpuChart"
+	case p.Series == analysis.MetricLoad1:
+		return "loadAvgChart"
+	default:
+		return "totalCpuChart"
+	}
+}
+
+// buildPeriodViews converts the detected periods, strongest first, and marks
+// the strongest one of each chart.
+func buildPeriodViews(periods []analysis.Periodicity) ([]PeriodView, []periodMarker) {
+	var views []PeriodView
+	markers := []periodMarker{}
+	marked := make(map[string]bool)
+	for _, p := range periods {
+		views = append(views, PeriodView{
+			Kind:        p.Kind,
+			Series:      p.Series,
+			Period:      p.Period.String(),
+			Strength:    p.Strength,
+			Recurrences: len(p.Peaks),
+			Threads:     p.Threads,
+		})
+		if chart := periodicityChart(p); !marked[chart] {
+			marked[chart] = true
+			markers = append(markers, periodMarker{
+				Chart: chart,
+				Label: fmt.Sprintf("every %s (%s)", p.Period, p.Series),
+				Peaks: p.Peaks,
+			})
+		}
+	}
+	return views, markers
+}
@@ -0,0 +1,27 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildPeriodViews(t *testing.T) {
+	views, markers := buildPeriodViews([]analysis.Periodicity{
+		{Kind: analysis.PeriodicityPool, Series: "refresh", Period: time.Minute, Strength: 0.9, Peaks: []int{2, 8}, Threads: []string{"refresh-1 (1)"}},
+		{Kind: analysis.PeriodicitySystem, Series: analysis.MetricTotalCPU, Period: time.Minute, Strength: 0.7, Peaks: []int{2, 8}},
+		{Kind: analysis.PeriodicityPool, Series: "gc", Period: 5 * time.Minute, Strength: 0.4, Peaks: []int{5}},
+	})
+	if len(views) != 3 || views[0].Period != "1m0s" || views[0].Recurrences != 2 || !reflect.DeepEqual(views[0].Threads, []string{"refresh-1 (1)"}) {
+		t.Errorf("unexpected views: %+v", views)
+	}
+	want := []periodMarker{
+		{Chart: "poolCpuChart", Label: "every 1m0s (refresh)", Peaks: []int{2, 8}},
+		{Chart: "totalCpuChart", Label: "every 1m0s (Total CPU)", Peaks: []int{2, 8}},
+	}
+	if !reflect.DeepEqual(markers, want) {
+		t.Errorf("expected only the strongest period per chart to be marked, got %+v", markers)
+	}
+}
@@ -0,0 +1,60 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// PhaseView is one phase of the capture as listed in the report.
+type PhaseView struct {
+	Name       string
+	TimeRange  string
+	Duration   string
+	Means      []float64 // in the order of PhaseMetrics
+	TopThreads []string
+}
+
+// phaseBand shades a phase on the charts.
+type phaseBand struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Label string `json:"label"`
+}
+
+// phaseMetrics are the column headers of the phase table.
+func phaseMetrics() []string {
+	var names []string
+	for _, m := range analysis.SystemMetrics {
+		name := m.Name
+		if m.Unit != "" {
+			name += " (" + m.Unit + ")"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// buildPhaseViews converts the phases for display, using times as the x axis
+// labels. A single phase gets no bands.
+func buildPhaseViews(phases []analysis.Phase, times []string) ([]PhaseView, []phaseBand) {
+	var views []PhaseView
+	bands := []phaseBand{}
+	for i, p := range phases {
+		view := PhaseView{
+			Name:      fmt.Sprintf("Phase %d", i+1),
+			TimeRange: times[p.Start] + " – " + times[p.End],
+			Duration:  p.Duration.Round(time.Second).String(),
+			Means:     p.Means,
+		}
+		for _, t := range p.TopThreads {
+			view.TopThreads = append(view.TopThreads, fmt.Sprintf("%s (%d) %.1f%%", t.Command, t.TID, t.MeanCPU))
+		}
+		views = append(views, view)
+		if len(phases) > 1 {
+			bands = append(bands, phaseBand{Start: p.Start, End: p.End, Label: view.Name})
+		}
+	}
+	return views, bands
+}
@@ -0,0 +1,37 @@
+package reporter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildPhaseViews(t *testing.T) {
+	times := []string{"12:00:00", "12:00:10", "12:00:20", "12:00:30"}
+	phases := []analysis.Phase{
+		{Start: 0, End: 1, Duration: 20 * time.Second, Means: []float64{5}},
+		{Start: 2, End: 3, Duration: 20 * time.Second, Means: []float64{60}, TopThreads: []analysis.PhaseThread{
+			{TID: 1, Command: "worker-1", MeanCPU: 90},
+		}},
+	}
+	views, bands := buildPhaseViews(phases, times)
+	if len(views) != 2 || views[1].Name != "Phase 2" || views[1].TimeRange != "12:00:20 – 12:00:30" || views[1].Duration != "20s" {
+		t.Errorf("unexpected views: %+v", views)
+	}
+	if want := []string{"worker-1 (1) 90.0%"}; !reflect.DeepEqual(views[1].TopThreads, want) {
+		t.Errorf("expected top threads %v, got %v", want, views[1].TopThreads)
+	}
+	if want := []phaseBand{{0, 1, "Phase 1"}, {2, 3, "Phase 2"}}; !reflect.DeepEqual(bands, want) {
+		t.Errorf("expected bands %v, got %v", want, bands)
+	}
+
+	_, bands = buildPhaseViews(phases[:1], times)
+	if len(bands) != 0 {
+		t.Errorf("expected no bands for a single phase, got %v", bands)
+	}
+	if got := phaseMetrics(); got[0] != "Total CPU (%)" || got[len(got)-1] != "Threads" {
+		t.Errorf("unexpected phase metric headers: %v", got)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
 	"github.com/rsvihladremio/threaded-top-reporter/parser"
 )
 
@@ -17,8 +19,13 @@ var templateFS embed.FS
 
 var tmpl *template.Template
 
+// templateFuncs are the helpers available to every template.
+var templateFuncs = template.FuncMap{
+	"percent": func(fraction float64) float64 { return fraction * 100 },
+}
+
 func init() {
-	tmpl = template.Must(template.New("base.html").ParseFS(templateFS, "templates/*.html"))
+	tmpl = template.Must(template.New("base.html").Funcs(templateFuncs).ParseFS(templateFS, "templates/*.html"))
 }
 
 type SnapshotView struct {
@@ -56,11 +63,114 @@ type ViewModel struct {
 	LoadAvg15Json        template.JS
 	ProcessNamesJson     template.JS
 	ProcessCpuSeriesJson template.JS
+	// OtherProcessListJson holds the threads folded into "other" as sparse
+	// series for the "show all" toggle, and null when there are none
+	OtherProcessListJson template.JS
 	Snapshots            []SnapshotView
+	Stylesheets          []AssetView
+	Scripts              []AssetView
+	CaptureBase64        string
+	ThreadStats          []analysis.ThreadStats
+	PoolCpuSeriesJson    template.JS
+	PoolStats            []analysis.PoolStats
+	Anomalies            []AnomalyView
+	AnomaliesJson        template.JS
+	Lifecycle            analysis.Lifecycle
+	LifecycleSeriesJson  template.JS
+	SwimlaneJson         template.JS
+	Blocked              []BlockedView
+	Saturation           SaturationView
+	// SaturationJson holds the host busy % and top thread %CPU per snapshot
+	SaturationJson template.JS
+	Memory         MemoryView
+	// MemorySeriesJson holds the process RES and trend lines added to the
+	// memory chart
+	MemorySeriesJson   template.JS
+	CategorySeriesJson template.JS
+	Categories         []CategoryShareView
+	Sampling           SamplingView
+	GapsJson           template.JS
+	Deviations         []DeviationView
+	BaselineBandsJson  template.JS
+	BaselineCaptures   int
+	BaselineSnapshots  int
+	Findings           []FindingView
+	Window             string
+	Filters            []string
+	HiddenThreads      int
+	Correlations       []analysis.Correlation
+	// CorrelationHeatmapJson holds the Pearson coefficient of the strongest
+	// threads and pools with each system metric
+	CorrelationHeatmapJson template.JS
+	Periods                []PeriodView
+	PeriodMarkersJson      template.JS
+	Phases                 []PhaseView
+	PhaseMetrics           []string
+	PhaseBandsJson         template.JS
+	AttributionJson        template.JS
+	AttributionHost        bool
+	SwimlaneMoreBlocked    int
+}
+
+// sparseSeries is a thread's %CPU as [snapshot, value] pairs for the
+// snapshots where it was busy. Most threads outside the top N are idle most of
+// the time, so this keeps the "show all" data small.
+type sparseSeries struct {
+	Name   string       `json:"name"`
+	Points [][2]float64 `json:"points"`
+}
+
+// CategoryShareView is a thread category's share of the listed threads' CPU.
+type CategoryShareView struct {
+	Name      string
+	MeanShare float64
+	PeakShare float64
+}
+
+// Options holds optional report settings. The zero value produces a fully
+// self-contained report.
+type Options struct {
+	CDN     bool   // load Bootstrap and ECharts from the CDN instead of inlining them
+	Capture []byte // raw input to embed in the report; nil leaves it out
+	// Pools groups threads into pools by normalized name; nil uses the
+	// built-in normalization only.
+	Pools *analysis.Normalizer
+	// TopN limits the per-thread CPU and attribution charts to the N highest
+	// ranked threads or pools plus an "other" series; 0 shows all of them.
+	TopN int
+	Rank analysis.RankMetric
+	// Anomalies tunes spike detection; the zero value uses the defaults.
+	Anomalies analysis.AnomalyConfig
+	// Categories classifies threads into categories such as GC or JIT; nil
+	// uses the built-in rules only.
+	Categories *analysis.Classifier
+	// Baseline scores the capture against a known good profile; nil skips
+	// the comparison.
+	Baseline *analysis.Profile
+	// Saturation tunes pegged thread and host saturation detection. A zero
+	// Cores is taken from per-core lines or the metadata when available.
+	Saturation analysis.SaturationConfig
+	// Rules produce the findings at the top of the report; nil runs the
+	// built-in rules.
+	Rules []analysis.Rule
+	// Window describes the part of the capture selected for the report, as
+	// returned by analysis.DescribeWindow; empty when it is the whole capture.
+	Window string
+	// Filters describes the thread filters applied to the capture, as
+	// returned by analysis.ThreadFilter.Describe, and HiddenThreads how many
+	// threads they hid.
+	Filters       []string
+	HiddenThreads int
 }
 
 // GenerateReport generates an HTML report to outputPath using parsed data.
-func GenerateReport(data parser.ReportData, outputPath, title, metadata, fileName, fileHash, appVersion string) (err error) {
+func GenerateReport(data parser.ReportData, outputPath, title, metadata, fileName, fileHash, appVersion string) error {
+	return GenerateReportWithOptions(data, outputPath, title, metadata, fileName, fileHash, appVersion, Options{})
+}
+
+// GenerateReportWithOptions generates an HTML report to outputPath using parsed
+// data and the given options.
+func GenerateReportWithOptions(data parser.ReportData, outputPath, title, metadata, fileName, fileHash, appVersion string, opts Options) (err error) {
 	// Sanitize output path
 	cleanOutput := filepath.Clean(outputPath)
 	if strings.Contains(cleanOutput, "..") {
@@ -71,27 +181,13 @@ func GenerateReport(data parser.ReportData, outputPath, title, metadata, fileNam
 	// build time-series and snapshot views
 	var times []string
 	var cpuUsers, cpuSystem, cpuIdle, cpuWait, cpuSteal []float64
-	var memTotal, memFree, memUsed, memBuffCache []float64
+	var memTotal, memFree, memUsed, memBuffCache, memAvail []float64
 	var swapTotal, swapFree, swapUsed []float64
 	var threadsTotal, threadsRunning, threadsSleeping, threadsStopped, threadsZombie []int
 	var loadAvg1, loadAvg5, loadAvg15 []float64
 	var snaps []SnapshotView
 
-	// Map to track processes by PID and store their CPU usage over time
-	processMap := make(map[int]map[string][]float64)
-	processNames := make(map[int]string)
-
-	// First pass: collect all process names and initialize tracking
-	for _, s := range data.Snapshots {
-		for _, p := range s.Processes {
-			if _, exists := processMap[p.PID]; !exists {
-				processMap[p.PID] = make(map[string][]float64)
-				processNames[p.PID] = fmt.Sprintf("%s-%d", p.Command, p.PID)
-			}
-		}
-	}
-
-	// Second pass: collect all data points
+	// Collect all data points
 	for _, s := range data.Snapshots {
 		t := s.Time.Format("15:04:05")
 		times = append(times, t)
@@ -108,6 +204,7 @@ func GenerateReport(data parser.ReportData, outputPath, title, metadata, fileNam
 		memFree = append(memFree, s.Metadata.MemFree)
 		memUsed = append(memUsed, s.Metadata.MemUsed)
 		memBuffCache = append(memBuffCache, s.Metadata.MemBuffCache)
+		memAvail = append(memAvail, s.Metadata.MemAvail)
 		swapTotal = append(swapTotal, s.Metadata.SwapTotal)
 		swapFree = append(swapFree, s.Metadata.SwapFree)
 		swapUsed = append(swapUsed, s.Metadata.SwapUsed)
@@ -129,37 +226,43 @@ func GenerateReport(data parser.ReportData, outputPath, title, metadata, fileNam
 			Time:         s.Time.Format("2006-01-02 15:04:05"),
 			ProcessCount: len(s.Processes),
 		})
+	}
 
-		// Track per-process CPU usage
-		// For each process seen in this snapshot
-		for _, p := range s.Processes {
-			if processData, exists := processMap[p.PID]; exists {
-				processData["cpu"] = append(processData["cpu"], p.CPU)
-			}
-		}
-
-		// Fill in zeros for processes not seen in this snapshot
-		for _, processData := range processMap {
-			snapIdx := len(times) - 1
-			if len(processData["cpu"]) < snapIdx+1 {
-				processData["cpu"] = append(processData["cpu"], 0)
-			}
+	// Generate process CPU series for ECharts: the top threads plus an "other"
+	// series, and the remaining threads for readers who expand the chart
+	threadSeries := func(t analysis.ThreadSeries) map[string]interface{} {
+		return map[string]interface{}{
+			"name": fmt.Sprintf("%s-%d", t.Command, t.TID),
+			"type": "line",
+			"data": t.CPU,
 		}
 	}
-
-	// Generate process CPU series for ECharts
 	var processNamesList []string
 	var processCpuSeries []map[string]interface{}
-
-	for pid, name := range processNames {
-		processNamesList = append(processNamesList, name)
-
-		series := map[string]interface{}{
-			"name": name,
-			"type": "line",
-			"data": processMap[pid]["cpu"],
+	top, rest, other := analysis.SplitTopThreads(data, opts.TopN, opts.Rank)
+	for _, t := range top {
+		processNamesList = append(processNamesList, fmt.Sprintf("%s-%d", t.Command, t.TID))
+		processCpuSeries = append(processCpuSeries, threadSeries(t))
+	}
+	var otherThreads []sparseSeries
+	if other != nil {
+		otherName := fmt.Sprintf("other (%d threads)", len(rest))
+		processNamesList = append(processNamesList, otherName)
+		processCpuSeries = append(processCpuSeries, map[string]interface{}{
+			"name":      otherName,
+			"type":      "line",
+			"data":      other,
+			"lineStyle": map[string]interface{}{"type": "dashed"},
+		})
+		for _, t := range rest {
+			sparse := sparseSeries{Name: fmt.Sprintf("%s-%d", t.Command, t.TID), Points: [][2]float64{}}
+			for i, v := range t.CPU {
+				if v != 0 {
+					sparse.Points = append(sparse.Points, [2]float64{float64(i), v})
+				}
+			}
+			otherThreads = append(otherThreads, sparse)
 		}
-		processCpuSeries = append(processCpuSeries, series)
 	}
 
 	// Marshal all data to JSON
@@ -265,6 +368,154 @@ func GenerateReport(data parser.ReportData, outputPath, title, metadata, fileNam
 	if err != nil {
 		return fmt.Errorf("marshal process cpu series: %w", err)
 	}
+	otJson, err := json.Marshal(otherThreads)
+	if err != nil {
+		return fmt.Errorf("marshal other thread series: %w", err)
+	}
+
+	// Thread pools, stacked in the pool chart
+	var poolSeries []map[string]interface{}
+	for _, p := range analysis.Pools(data, opts.Pools) {
+		poolSeries = append(poolSeries, map[string]interface{}{
+			"name":      p.Name,
+			"type":      "line",
+			"stack":     "pools",
+			"areaStyle": map[string]interface{}{},
+			"symbol":    "none",
+			"data":      p.CPU,
+		})
+	}
+	poolJson, err := json.Marshal(poolSeries)
+	if err != nil {
+		return fmt.Errorf("marshal pool cpu series: %w", err)
+	}
+
+	// Thread categories, stacked as a share of all listed threads' CPU
+	var categorySeries []map[string]interface{}
+	var categoryShares []CategoryShareView
+	for _, c := range analysis.Categories(data, opts.Categories) {
+		categorySeries = append(categorySeries, map[string]interface{}{
+			"name":      c.Name,
+			"type":      "line",
+			"stack":     "categories",
+			"areaStyle": map[string]interface{}{},
+			"symbol":    "none",
+			"data":      c.Share,
+		})
+		peak := 0.0
+		for _, v := range c.Share {
+			peak = math.Max(peak, v)
+		}
+		categoryShares = append(categoryShares, CategoryShareView{Name: c.Name, MeanShare: analysis.Mean(c.Share), PeakShare: peak})
+	}
+	categoryJson, err := json.Marshal(categorySeries)
+	if err != nil {
+		return fmt.Errorf("marshal category cpu series: %w", err)
+	}
+
+	sampling := buildSamplingView(analysis.AnalyzeSampling(data), times)
+	gapsJson, err := json.Marshal(sampling.Gaps)
+	if err != nil {
+		return fmt.Errorf("marshal sampling gaps: %w", err)
+	}
+
+	var deviations []DeviationView
+	var baselineCaptures, baselineSnapshots int
+	var bands []BaselineBand
+	if opts.Baseline != nil {
+		deviations, bands = buildBaselineViews(analysis.ScoreAgainst(data, *opts.Baseline, opts.Pools))
+		baselineCaptures, baselineSnapshots = opts.Baseline.Captures, opts.Baseline.Snapshots
+	}
+	bandsJson, err := json.Marshal(bands)
+	if err != nil {
+		return fmt.Errorf("marshal baseline bands: %w", err)
+	}
+
+	anomalies := buildAnomalyViews(analysis.DetectAnomalies(data, opts.Anomalies), times, top, other)
+	anomaliesJson, err := json.Marshal(anomalies)
+	if err != nil {
+		return fmt.Errorf("marshal anomalies: %w", err)
+	}
+
+	swimlane := buildSwimlane(top, analysis.Threads(data), opts.TopN)
+	swimlaneJson, err := json.Marshal(swimlane)
+	if err != nil {
+		return fmt.Errorf("marshal state swimlane: %w", err)
+	}
+
+	satCfg := opts.Saturation
+	var coresSource string
+	satCfg.Cores, coresSource = analysis.CoreCount(data, satCfg.Cores, metadata)
+	saturation := analysis.DetectSaturation(data, satCfg)
+	saturationJson, err := json.Marshal(map[string]interface{}{
+		"hostBusy": saturation.HostBusy,
+		"topCpu":   saturation.TopCPU,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal saturation series: %w", err)
+	}
+
+	rules := opts.Rules
+	if rules == nil {
+		rules = analysis.BuiltinRules(opts.Saturation)
+	}
+	findings := analysis.RunRules(analysis.RuleInput{Data: data, Cores: satCfg.Cores}, rules)
+
+	// Who used the CPU: the top threads and pools stacked against the host
+	attribution := analysis.AttributionConfig{TopN: opts.TopN, Rank: opts.Rank, Pools: opts.Pools, Cores: satCfg.Cores}
+	attribution.By = analysis.AttributeThreads
+	byThread := analysis.AttributeCPU(data, attribution)
+	attribution.By = analysis.AttributePools
+	byPool := analysis.AttributeCPU(data, attribution)
+	attributionJson, err := json.Marshal(map[string]interface{}{
+		"threads": attributionSeries(byThread),
+		"pools":   attributionSeries(byPool),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal cpu attribution series: %w", err)
+	}
+
+	memory, memorySeries := buildMemoryView(analysis.MemoryTrends(data, analysis.MemoryTrendConfig{}), memAvail, analysis.ProcessRES(data))
+	memoryJson, err := json.Marshal(memorySeries)
+	if err != nil {
+		return fmt.Errorf("marshal memory trend series: %w", err)
+	}
+
+	correlations, heatmap := buildCorrelationViews(analysis.Correlations(data, opts.Pools))
+	heatmapJson, err := json.Marshal(heatmap)
+	if err != nil {
+		return fmt.Errorf("marshal correlation heatmap: %w", err)
+	}
+
+	periods, periodMarkers := buildPeriodViews(analysis.DetectPeriodicity(data, opts.Pools, analysis.PeriodicityConfig{}))
+	periodMarkersJson, err := json.Marshal(periodMarkers)
+	if err != nil {
+		return fmt.Errorf("marshal periodicity markers: %w", err)
+	}
+
+	phases, phaseBands := buildPhaseViews(analysis.DetectPhases(data, analysis.PhaseConfig{}), times)
+	phaseBandsJson, err := json.Marshal(phaseBands)
+	if err != nil {
+		return fmt.Errorf("marshal phase bands: %w", err)
+	}
+
+	lifecycle := analysis.AnalyzeLifecycle(data, opts.Pools, analysis.LifecycleConfig{})
+	lifecycleJson, err := json.Marshal(lifecycleSeries(lifecycle, len(data.Snapshots)))
+	if err != nil {
+		return fmt.Errorf("marshal lifecycle series: %w", err)
+	}
+
+	styles, scripts, err := buildAssetViews(opts.CDN)
+	if err != nil {
+		return fmt.Errorf("load web assets: %w", err)
+	}
+
+	var captureB64 string
+	if opts.Capture != nil {
+		if captureB64, err = encodeCapture(opts.Capture); err != nil {
+			return err
+		}
+	}
 
 	// compute short SHA for display
 	fileHashShort := fileHash
@@ -302,8 +553,47 @@ func GenerateReport(data parser.ReportData, outputPath, title, metadata, fileNam
 		LoadAvg15Json:        template.JS(string(la15Json)), // #nosec G203: safe – marshaled JSON only contains numbers
 		ProcessNamesJson:     template.JS(string(pnJson)),   // #nosec G203: safe – marshaled JSON only contains numbers and process names
 		ProcessCpuSeriesJson: template.JS(string(pcsJson)),  // #nosec G203: safe – marshaled JSON only contains numbers
+		OtherProcessListJson: template.JS(string(otJson)),   // #nosec G203: safe – marshaled JSON only contains numbers and process names
 		Snapshots:            snaps,
-	}
+		Stylesheets:          styles,
+		Scripts:              scripts,
+		CaptureBase64:        captureB64,
+		ThreadStats:          analysis.ComputeThreadStats(data),
+		PoolCpuSeriesJson:    template.JS(string(poolJson)), // #nosec G203: safe – marshaled JSON only contains numbers and pool names
+		PoolStats:            analysis.ComputePoolStats(data, opts.Pools),
+		Anomalies:            anomalies,
+		AnomaliesJson:        template.JS(string(anomaliesJson)), // #nosec G203: safe – marshaled JSON only contains numbers, chart ids and labels
+		Lifecycle:            lifecycle,
+		LifecycleSeriesJson:  template.JS(string(lifecycleJson)), // #nosec G203: safe – marshaled JSON only contains numbers and pool names
+		SwimlaneJson:         template.JS(string(swimlaneJson)),  // #nosec G203: safe – marshaled JSON only contains numbers, states and thread names
+		Blocked:              buildBlockedViews(analysis.BlockedPeriods(data), times),
+		Saturation:           buildSaturationView(saturation, coresSource, times),
+		SaturationJson:       template.JS(string(saturationJson)), // #nosec G203: safe – marshaled JSON only contains numbers
+		Memory:               memory,
+		MemorySeriesJson:     template.JS(string(memoryJson)),   // #nosec G203: safe – marshaled JSON only contains numbers and metric names
+		CategorySeriesJson:   template.JS(string(categoryJson)), // #nosec G203: safe – marshaled JSON only contains numbers and category names
+		Categories:           categoryShares,
+		Sampling:             sampling,
+		GapsJson:             template.JS(string(gapsJson)), // #nosec G203: safe – marshaled JSON only contains numbers and durations
+		Deviations:           deviations,
+		BaselineCaptures:     baselineCaptures,
+		BaselineSnapshots:    baselineSnapshots,
+		BaselineBandsJson:    template.JS(string(bandsJson)), // #nosec G203: safe – marshaled JSON only contains numbers, chart ids and metric names
+		Findings:             buildFindingViews(findings, times),
+		Window:               opts.Window,
+		Filters:              opts.Filters,
+		HiddenThreads:        opts.HiddenThreads,
+		Correlations:         correlations,
+		Periods:              periods,
+		Phases:               phases,
+		PhaseMetrics:         phaseMetrics(),
+		AttributionHost:      byThread.Host != nil,
+		SwimlaneMoreBlocked:  swimlane.MoreBlocked,
+	}
+	vm.CorrelationHeatmapJson = template.JS(string(heatmapJson))  // #nosec G203: safe – marshaled JSON only contains numbers, metric names and thread names
+	vm.PeriodMarkersJson = template.JS(string(periodMarkersJson)) // #nosec G203: safe – marshaled JSON only contains numbers, chart ids and labels
+	vm.PhaseBandsJson = template.JS(string(phaseBandsJson))       // #nosec G203: safe – marshaled JSON only contains numbers and phase names
+	vm.AttributionJson = template.JS(string(attributionJson))     // #nosec G203: safe – marshaled JSON only contains numbers, thread names and chart styling
 
 	// ensure directory
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0750); err != nil {
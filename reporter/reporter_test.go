@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
 	"github.com/rsvihladremio/threaded-top-reporter/parser"
 )
 
@@ -85,6 +86,36 @@ func TestGenerateReport_HappyPath(t *testing.T) {
 	if !strings.Contains(html, "abc123") {
 		t.Error("missing file hash")
 	}
+	if !strings.Contains(html, `id="threadStatsTable"`) || !strings.Contains(html, "<td>123</td>") {
+		t.Error("per-thread statistics table not found")
+	}
+	if !strings.Contains(html, `"stack":"pools"`) || !strings.Contains(html, `id="poolStatsTable"`) {
+		t.Error("thread pool chart or table not found")
+	}
+	if !strings.Contains(html, `id="lifecycleChart"`) || !strings.Contains(html, "no sustained growth") {
+		t.Error("thread lifecycle section not found")
+	}
+	if !strings.Contains(html, `id="stateSwimlaneChart"`) || !strings.Contains(html, `"runs":[[0,0,1,""]]`) {
+		t.Error("thread state swimlane not found")
+	}
+	if !strings.Contains(html, `id="saturationChart"`) || !strings.Contains(html, "Core count unknown") {
+		t.Error("CPU saturation section not found")
+	}
+	if !strings.Contains(html, `id="memoryTrend"`) {
+		t.Error("memory trend section not found")
+	}
+	if !strings.Contains(html, `"stack":"categories"`) || !strings.Contains(html, "Application 0%") {
+		t.Error("CPU share by category chart not found")
+	}
+	if !strings.Contains(html, "every <strong>5m0s</strong>") {
+		t.Error("sampling panel not found")
+	}
+	if !strings.Contains(html, `id="findings"`) || !strings.Contains(html, "found a problem") {
+		t.Error("findings section not found")
+	}
+	if !strings.Contains(html, `id="correlationHeatmap"`) || !strings.Contains(html, `{"subjects":[],"metrics":["IOWait","Steal","System CPU","Load 1m","Running threads"],"cells":[]}`) {
+		t.Error("correlation heatmap not found")
+	}
 
 }
 
@@ -123,3 +154,244 @@ func TestGenerateReport_EscapesTitleAndMetadata(t *testing.T) {
 		t.Error("escaped metadata not found")
 	}
 }
+
+func TestGenerateReport_TopN(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	snap := parser.Snapshot{
+		Time: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		Processes: []parser.ProcessData{
+			{PID: 1, Command: "hot", CPU: 90},
+			{PID: 2, Command: "warm", CPU: 30},
+			{PID: 3, Command: "cool", CPU: 5},
+		},
+	}
+	data := parser.ReportData{Snapshots: []parser.Snapshot{snap}}
+	opts := Options{TopN: 1, Rank: analysis.RankAverage}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", opts); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	if !strings.Contains(html, `[{"data":[90],"name":"hot-1","type":"line"},{"data":[35]`) {
+		t.Error("top thread followed by other series not found")
+	}
+	if !strings.Contains(html, `"name":"other (2 threads)","type":"line"`) || !strings.Contains(html, `"data":[35]`) {
+		t.Error("other series should sum the remaining threads")
+	}
+	if !strings.Contains(html, `[{"name":"warm-2","points":[[0,30]]},{"name":"cool-3","points":[[0,5]]}]`) {
+		t.Error("other threads not embedded as sparse series for expansion")
+	}
+	if n := strings.Count(html, `"name":"hot-1"`); n != 1 {
+		t.Errorf("top thread embedded %d times, want once", n)
+	}
+}
+
+func TestGenerateReport_Baseline(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	snap := parser.Snapshot{
+		Time:      time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		Metadata:  parser.Metadata{CPUWait: 1},
+		Processes: []parser.ProcessData{{PID: 1, Command: "worker-1", CPU: 20}},
+	}
+	profile := analysis.BuildProfile([]parser.ReportData{{Snapshots: []parser.Snapshot{snap, snap}}}, nil)
+	snap.Metadata.CPUWait = 40
+	data := parser.ReportData{Snapshots: []parser.Snapshot{snap}}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", Options{Baseline: &profile}); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	if !strings.Contains(html, `id="deviationTable"`) || !strings.Contains(html, "2 snapshots from 1 capture.") {
+		t.Error("baseline deviation table not found")
+	}
+	if !strings.Contains(html, `{"chart":"totalCpuChart","low":1,"high":1,"label":"baseline IOWait"}`) {
+		t.Error("baseline band for iowait not found")
+	}
+}
+
+type staticRule []analysis.Finding
+
+func (staticRule) Name() string                                     { return "static" }
+func (r staticRule) Evaluate(analysis.RuleInput) []analysis.Finding { return r }
+
+func TestGenerateReport_Findings(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	data := parser.ReportData{Snapshots: []parser.Snapshot{{Time: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)}}}
+	rules := []analysis.Rule{staticRule{{
+		Severity:    analysis.SeverityCritical,
+		Title:       "Disk on fire",
+		Explanation: "Everything waits on I/O.",
+		Threads:     []string{"writer (1)"},
+	}}}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", Options{Rules: rules}); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	for _, want := range []string{`alert alert-danger`, "<strong>Disk on fire</strong>", "Everything waits on I/O.", "<code>writer (1)</code>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the findings section", want)
+		}
+	}
+}
+
+func TestGenerateReport_Window(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	data := parser.ReportData{Snapshots: []parser.Snapshot{{Time: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)}}}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", Options{Window: "12:00:00 – 12:00:00, 1 of 10 snapshots"}); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(b), "Window: 12:00:00 – 12:00:00, 1 of 10 snapshots") {
+		t.Error("selected window not recorded in the header")
+	}
+}
+
+func TestGenerateReport_Filters(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	data := parser.ReportData{Snapshots: []parser.Snapshot{{Time: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)}}}
+	opts := Options{Filters: []string{"user = dremio", "command !~ /^GC/"}, HiddenThreads: 7}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", opts); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(b), "Threads: user = dremio; command !~ /^GC/ (7 hidden)") {
+		t.Error("thread filters not recorded in the header")
+	}
+}
+
+func TestGenerateReport_Periodicity(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	var data parser.ReportData
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		cpu := 1.0
+		if i%6 == 0 {
+			cpu = 90
+		}
+		data.Snapshots = append(data.Snapshots, parser.Snapshot{
+			Time:      start.Add(time.Duration(i) * 10 * time.Second),
+			Metadata:  parser.Metadata{CPUUser: cpu / 2},
+			Processes: []parser.ProcessData{{PID: 1, Command: "refresh", CPU: cpu}},
+		})
+	}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", Options{}); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	if !strings.Contains(html, `id="periodTable"`) || !strings.Contains(html, "<td>1m0s</td>") || !strings.Contains(html, "<code>refresh (1)</code>") {
+		t.Error("recurring pattern table not found")
+	}
+	if !strings.Contains(html, `{"chart":"totalCpuChart","label":"every 1m0s (Total CPU)","peaks":[0,6,12,18,24]}`) {
+		t.Error("recurrence markers for total CPU not found")
+	}
+}
+
+func TestGenerateReport_Phases(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	var data parser.ReportData
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		cpu := 2.0 + float64(i%2)
+		if i >= 15 {
+			cpu = 80 + float64(i%2)
+		}
+		data.Snapshots = append(data.Snapshots, parser.Snapshot{
+			Time:      start.Add(time.Duration(i) * 10 * time.Second),
+			Metadata:  parser.Metadata{CPUUser: cpu},
+			Processes: []parser.ProcessData{{PID: 1, Command: "worker", CPU: cpu}},
+		})
+	}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", Options{}); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	if !strings.Contains(html, `id="phaseTable"`) || !strings.Contains(html, "<td>12:02:30 – 12:04:50</td>") || !strings.Contains(html, "worker (1) 80.5%") {
+		t.Error("phase table not found")
+	}
+	if !strings.Contains(html, `[{"start":0,"end":14,"label":"Phase 1"},{"start":15,"end":29,"label":"Phase 2"}]`) {
+		t.Error("phase bands not found")
+	}
+}
+
+func TestGenerateReport_Attribution(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	data := parser.ReportData{Snapshots: []parser.Snapshot{{
+		Time:     start,
+		Metadata: parser.Metadata{CPUUser: 40, CPUSystem: 10, CPUIdle: 50},
+		Processes: []parser.ProcessData{
+			{PID: 1, Command: "worker-1", CPU: 90},
+			{PID: 2, Command: "worker-2", CPU: 30},
+		},
+	}}}
+	opts := Options{TopN: 1, Saturation: analysis.SaturationConfig{Cores: 4}}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", opts); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	if !strings.Contains(html, `id="attributionChart"`) || !strings.Contains(html, `"name":"worker-1 (1)"`) {
+		t.Error("attribution chart not found")
+	}
+	if !strings.Contains(html, `"data":[30],"itemStyle":{"color":"#adb5bd"},"name":"other"`) {
+		t.Error("other band not found")
+	}
+	if !strings.Contains(html, `"data":[200],"itemStyle":{"color":"#212529"}`) {
+		t.Error("host cpu line not scaled by core count")
+	}
+}
+
+func TestGenerateReport_SwimlaneBlockedCap(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.html")
+	snap := parser.Snapshot{
+		Time: time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+		Processes: []parser.ProcessData{
+			{PID: 1, Command: "hot", CPU: 90, S: "R"},
+			{PID: 2, Command: "io-1", S: "D"},
+			{PID: 3, Command: "io-2", S: "D"},
+			{PID: 4, Command: "io-3", S: "D"},
+		},
+	}
+	data := parser.ReportData{Snapshots: []parser.Snapshot{snap}}
+	if err := GenerateReportWithOptions(data, out, "t", "", "input.top", "abc123", "", Options{TopN: 1}); err != nil {
+		t.Fatalf("GenerateReportWithOptions failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	if !strings.Contains(html, `"rows":["hot (1)","io-1 (2)"]`) {
+		t.Error("expected one blocked row after the top thread")
+	}
+	if !strings.Contains(html, "+2 more blocked threads") {
+		t.Error("note on the blocked threads without a row not found")
+	}
+}
@@ -0,0 +1,53 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// SamplingView describes the capture's sampling regularity.
+type SamplingView struct {
+	Nominal    string
+	Jitter     string
+	Irregular  int
+	Missing    int
+	Gaps       []GapView
+	Duplicates []string // times repeated from the snapshot before
+}
+
+// GapView is a sampling gap as listed in the report and shaded on the charts.
+type GapView struct {
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	Label     string `json:"label"`
+	TimeRange string `json:"-"`
+	Interval  string `json:"-"`
+	Missing   int    `json:"-"`
+}
+
+// buildSamplingView converts the sampling analysis for display, using times
+// as the x axis labels.
+func buildSamplingView(s analysis.Sampling, times []string) SamplingView {
+	view := SamplingView{
+		Nominal:   s.Nominal.String(),
+		Jitter:    s.Jitter.Round(time.Millisecond).String(),
+		Irregular: s.Irregular,
+		Missing:   s.Missing,
+	}
+	for _, g := range s.Gaps {
+		view.Gaps = append(view.Gaps, GapView{
+			Start:     g.After,
+			End:       g.After + 1,
+			Label:     fmt.Sprintf("gap: %s, ~%d missing", g.Interval, g.Missing),
+			TimeRange: times[g.After] + " – " + times[g.After+1],
+			Interval:  g.Interval.String(),
+			Missing:   g.Missing,
+		})
+	}
+	for _, i := range s.Duplicates {
+		view.Duplicates = append(view.Duplicates, times[i])
+	}
+	return view
+}
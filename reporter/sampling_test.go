@@ -0,0 +1,28 @@
+package reporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildSamplingView(t *testing.T) {
+	times := []string{"12:00:00", "12:00:02", "12:00:12", "12:00:12"}
+	view := buildSamplingView(analysis.Sampling{
+		Nominal:    2 * time.Second,
+		Jitter:     time.Second / 6,
+		Gaps:       []analysis.SamplingGap{{After: 1, Interval: 10 * time.Second, Missing: 4}},
+		Duplicates: []int{3},
+		Missing:    4,
+	}, times)
+	if view.Nominal != "2s" || view.Jitter != "167ms" {
+		t.Errorf("unexpected interval summary: %+v", view)
+	}
+	if len(view.Gaps) != 1 || view.Gaps[0].TimeRange != "12:00:02 – 12:00:12" || view.Gaps[0].Label != "gap: 10s, ~4 missing" {
+		t.Errorf("unexpected gaps: %+v", view.Gaps)
+	}
+	if len(view.Duplicates) != 1 || view.Duplicates[0] != "12:00:12" {
+		t.Errorf("unexpected duplicates: %v", view.Duplicates)
+	}
+}
@@ -0,0 +1,78 @@
+package reporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// SaturationView is the CPU saturation verdict and the evidence behind it.
+type SaturationView struct {
+	Verdict     string
+	Host        bool // the host itself was saturated, not just one thread
+	Summary     string
+	Cores       int
+	CoresSource string
+	Periods     []SaturationPeriodView
+}
+
+// SaturationPeriodView is one pegged thread or saturated host period.
+type SaturationPeriodView struct {
+	Subject   string
+	TimeRange string
+	Duration  string
+	MeanCPU   float64
+	HostBusy  float64
+}
+
+// buildSaturationView explains the saturation analysis in words, using times
+// as the x axis labels.
+func buildSaturationView(sat analysis.Saturation, coresSource string, times []string) SaturationView {
+	view := SaturationView{
+		Verdict:     sat.Verdict,
+		Host:        sat.Verdict == analysis.VerdictHost,
+		Cores:       sat.Cores,
+		CoresSource: coresSource,
+	}
+	timeRange := func(p analysis.SaturationPeriod) string {
+		if p.End == p.Start {
+			return times[p.Start]
+		}
+		return times[p.Start] + " – " + times[p.End]
+	}
+	for _, p := range sat.Host {
+		view.Periods = append(view.Periods, SaturationPeriodView{
+			Subject:   "host",
+			TimeRange: timeRange(p),
+			Duration:  p.Duration.Round(time.Second).String(),
+			MeanCPU:   p.MeanCPU,
+			HostBusy:  p.HostBusy,
+		})
+	}
+	for _, p := range sat.Pegged {
+		view.Periods = append(view.Periods, SaturationPeriodView{
+			Subject:   fmt.Sprintf("%s (%d)", p.Command, p.TID),
+			TimeRange: timeRange(p),
+			Duration:  p.Duration.Round(time.Second).String(),
+			MeanCPU:   p.MeanCPU,
+			HostBusy:  p.HostBusy,
+		})
+	}
+
+	cores := "an unknown number of cores"
+	if sat.Cores > 0 {
+		cores = fmt.Sprintf("%d cores", sat.Cores)
+	}
+	switch sat.Verdict {
+	case analysis.VerdictHost:
+		p := sat.Host[0]
+		view.Summary = fmt.Sprintf("Host CPU saturated: %.0f%% busy across %s for %s from %s.",
+			p.MeanCPU, cores, p.Duration.Round(time.Second), times[p.Start])
+	case analysis.VerdictSingleThread:
+		p := sat.Pegged[0]
+		view.Summary = fmt.Sprintf("Saturated by a single thread: %s (%d) ran at %.1f%% for %s from %s while the host was only %.0f%% busy across %s.",
+			p.Command, p.TID, p.MeanCPU, p.Duration.Round(time.Second), times[p.Start], p.HostBusy, cores)
+	}
+	return view
+}
@@ -0,0 +1,37 @@
+package reporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildSaturationView(t *testing.T) {
+	times := []string{"12:00:00", "12:00:30", "12:01:00"}
+	sat := analysis.Saturation{
+		Cores:   16,
+		Verdict: analysis.VerdictSingleThread,
+		Pegged: []analysis.SaturationPeriod{
+			{TID: 42, Command: "planner", Start: 0, End: 1, Duration: time.Minute, MeanCPU: 99.5, HostBusy: 8},
+		},
+	}
+	view := buildSaturationView(sat, analysis.CoresFromPerCore, times)
+	want := "Saturated by a single thread: planner (42) ran at 99.5% for 1m0s from 12:00:00 while the host was only 8% busy across 16 cores."
+	if view.Summary != want {
+		t.Errorf("unexpected summary:\n got %s\nwant %s", view.Summary, want)
+	}
+	if len(view.Periods) != 1 || view.Periods[0].Subject != "planner (42)" || view.Periods[0].TimeRange != "12:00:00 – 12:00:30" {
+		t.Errorf("unexpected periods: %+v", view.Periods)
+	}
+
+	sat = analysis.Saturation{
+		Verdict: analysis.VerdictHost,
+		Host:    []analysis.SaturationPeriod{{Start: 1, End: 2, Duration: time.Minute, MeanCPU: 97, HostBusy: 97}},
+	}
+	view = buildSaturationView(sat, "", times)
+	if !strings.HasPrefix(view.Summary, "Host CPU saturated: 97% busy across an unknown number of cores") {
+		t.Errorf("unexpected summary: %s", view.Summary)
+	}
+}
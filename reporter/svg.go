@@ -0,0 +1,344 @@
+package reporter
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// palette matches the default ECharts colors so static and interactive
+// reports look alike.
+var palette = []string{"#5470c6", "#91cc75", "#fac858", "#ee6666", "#73c0de", "#3ba272", "#fc8452", "#9a60b4", "#ea7ccc"}
+
+const (
+	svgWidth        = 900
+	svgPlotHeight   = 240
+	svgMarginLeft   = 60
+	svgMarginRight  = 20
+	svgMarginTop    = 36
+	svgAxisHeight   = 40
+	svgLegendRow    = 18
+	svgLegendColumn = 220
+	svgMaxLegend    = 12
+)
+
+// svgSeries is a named line in a static chart.
+type svgSeries struct {
+	Name   string
+	Values []float64
+}
+
+// svgChart describes a static line chart over the snapshot times.
+type svgChart struct {
+	File   string // file name used when written as a standalone SVG
+	Title  string
+	YLabel string
+	Times  []string
+	Series []svgSeries
+}
+
+// StaticChart is a rendered chart embedded in the script-free report.
+type StaticChart struct {
+	Title string
+	SVG   template.HTML
+}
+
+// StaticOptions tunes the script-free report and the standalone SVG charts.
+type StaticOptions struct {
+	// TopN limits the per-thread CPU chart to the N highest ranked threads
+	// plus an "other" series; 0 draws every thread.
+	TopN int
+	// Rank orders the threads; the zero value ranks by average CPU.
+	Rank analysis.RankMetric
+	// Window describes the part of the capture selected for the report, as
+	// returned by analysis.DescribeWindow; empty when it is the whole capture.
+	Window string
+	// Filters describes the thread filters applied to the capture, as
+	// returned by analysis.ThreadFilter.Describe, and HiddenThreads how many
+	// threads they hid.
+	Filters       []string
+	HiddenThreads int
+}
+
+// StaticViewModel is the data rendered by static.html.
+type StaticViewModel struct {
+	Title         string
+	Metadata      string
+	AppVersion    string
+	FileName      string
+	FileHash      string
+	FileHashShort string
+	Window        string
+	Filters       []string
+	HiddenThreads int
+	Charts        []StaticChart
+}
+
+// buildSVGCharts assembles the five report charts from the parsed data.
+func buildSVGCharts(data parser.ReportData, opts StaticOptions) []svgChart {
+	var times []string
+	metric := func(get func(parser.Metadata) float64) []float64 {
+		values := make([]float64, len(data.Snapshots))
+		for i, s := range data.Snapshots {
+			values[i] = get(s.Metadata)
+		}
+		return values
+	}
+	for _, s := range data.Snapshots {
+		times = append(times, s.Time.Format("15:04:05"))
+	}
+
+	return []svgChart{
+		{
+			File: "per-thread-cpu.svg", Title: "Per-Process CPU Usage", YLabel: "% CPU", Times: times,
+			Series: threadCPUSeries(data, opts),
+		},
+		{
+			File: "load-average.svg", Title: "Load Average", YLabel: "Load", Times: times,
+			Series: []svgSeries{
+				{"1 min", metric(func(m parser.Metadata) float64 { return m.LoadAvg1 })},
+				{"5 min", metric(func(m parser.Metadata) float64 { return m.LoadAvg5 })},
+				{"15 min", metric(func(m parser.Metadata) float64 { return m.LoadAvg15 })},
+			},
+		},
+		{
+			File: "thread-states.svg", Title: "Thread States", YLabel: "Count", Times: times,
+			Series: []svgSeries{
+				{"Total", metric(func(m parser.Metadata) float64 { return float64(m.ThreadsTotal) })},
+				{"Running", metric(func(m parser.Metadata) float64 { return float64(m.ThreadsRunning) })},
+				{"Sleeping", metric(func(m parser.Metadata) float64 { return float64(m.ThreadsSleeping) })},
+				{"Stopped", metric(func(m parser.Metadata) float64 { return float64(m.ThreadsStopped) })},
+				{"Zombie", metric(func(m parser.Metadata) float64 { return float64(m.ThreadsZombie) })},
+			},
+		},
+		{
+			File: "memory-usage.svg", Title: "Memory Usage", YLabel: "MiB", Times: times,
+			Series: []svgSeries{
+				{"Total", metric(func(m parser.Metadata) float64 { return m.MemTotal })},
+				{"Free", metric(func(m parser.Metadata) float64 { return m.MemFree })},
+				{"Used", metric(func(m parser.Metadata) float64 { return m.MemUsed })},
+				{"Buff/Cache", metric(func(m parser.Metadata) float64 { return m.MemBuffCache })},
+				{"Swap Total", metric(func(m parser.Metadata) float64 { return m.SwapTotal })},
+				{"Swap Free", metric(func(m parser.Metadata) float64 { return m.SwapFree })},
+				{"Swap Used", metric(func(m parser.Metadata) float64 { return m.SwapUsed })},
+			},
+		},
+		{
+			File: "total-cpu.svg", Title: "Total CPU Usage", YLabel: "% CPU", Times: times,
+			Series: []svgSeries{
+				{"User", metric(func(m parser.Metadata) float64 { return m.CPUUser })},
+				{"System", metric(func(m parser.Metadata) float64 { return m.CPUSystem })},
+				{"Idle", metric(func(m parser.Metadata) float64 { return m.CPUIdle })},
+				{"IOWait", metric(func(m parser.Metadata) float64 { return m.CPUWait })},
+				{"Steal", metric(func(m parser.Metadata) float64 { return m.CPUSteal })},
+			},
+		},
+	}
+}
+
+// threadCPUSeries returns the top ranked threads, busiest first, plus an
+// "other" series holding the rest like the interactive chart.
+func threadCPUSeries(data parser.ReportData, opts StaticOptions) []svgSeries {
+	var series []svgSeries
+	top, other := analysis.TopThreads(data, opts.TopN, opts.Rank)
+	for _, t := range top {
+		series = append(series, svgSeries{Name: fmt.Sprintf("%s (%d)", t.Command, t.TID), Values: t.CPU})
+	}
+	if other != nil {
+		hidden := len(analysis.Threads(data)) - len(top)
+		series = append(series, svgSeries{Name: fmt.Sprintf("other (%d threads)", hidden), Values: other})
+	}
+	return series
+}
+
+// renderSVG draws the chart as a standalone SVG document.
+func renderSVG(c svgChart) string {
+	legendItems := len(c.Series)
+	if legendItems > svgMaxLegend {
+		legendItems = svgMaxLegend + 1 // the last slot notes the hidden entries
+	}
+	perRow := (svgWidth - svgMarginLeft) / svgLegendColumn
+	legendRows := (legendItems + perRow - 1) / perRow
+	height := svgMarginTop + svgPlotHeight + svgAxisHeight + legendRows*svgLegendRow + 10
+	plotW := float64(svgWidth - svgMarginLeft - svgMarginRight)
+	plotH := float64(svgPlotHeight)
+
+	yMax := 0.0
+	for _, s := range c.Series {
+		yMax = math.Max(yMax, maxOf(s.Values))
+	}
+	yMax = niceCeil(yMax)
+
+	x := func(i int) float64 {
+		if len(c.Times) <= 1 {
+			return svgMarginLeft + plotW/2
+		}
+		return svgMarginLeft + float64(i)*plotW/float64(len(c.Times)-1)
+	}
+	y := func(v float64) float64 {
+		return svgMarginTop + plotH - v/yMax*plotH
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`, svgWidth, height, svgWidth, height)
+	b.WriteString("\n")
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", svgWidth, height)
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", svgMarginLeft, html.EscapeString(c.Title))
+	fmt.Fprintf(&b, `<text x="12" y="%d" transform="rotate(-90 12 %d)" text-anchor="middle" fill="#666">%s</text>`+"\n",
+		svgMarginTop+svgPlotHeight/2, svgMarginTop+svgPlotHeight/2, html.EscapeString(c.YLabel))
+
+	// y grid and labels
+	const yTicks = 5
+	for i := 0; i <= yTicks; i++ {
+		v := yMax * float64(i) / yTicks
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#e0e6f1"/>`+"\n", svgMarginLeft, y(v), svgWidth-svgMarginRight, y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" fill="#666">%s</text>`+"\n", svgMarginLeft-6, y(v)+4, formatTick(v))
+	}
+
+	// x labels, at most ~10 of them
+	step := 1
+	if len(c.Times) > 10 {
+		step = (len(c.Times) + 9) / 10
+	}
+	for i := 0; i < len(c.Times); i += step {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="#666">%s</text>`+"\n", x(i), svgMarginTop+svgPlotHeight+16, html.EscapeString(c.Times[i]))
+	}
+
+	// series
+	for i, s := range c.Series {
+		color := palette[i%len(palette)]
+		var points []string
+		for j, v := range s.Values {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(j), y(v)))
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="%s"><title>%s</title></polyline>`+"\n",
+			color, strings.Join(points, " "), html.EscapeString(s.Name))
+	}
+
+	// legend
+	legendTop := svgMarginTop + svgPlotHeight + svgAxisHeight
+	for i := 0; i < legendItems; i++ {
+		lx := svgMarginLeft + (i%perRow)*svgLegendColumn
+		ly := legendTop + (i/perRow)*svgLegendRow
+		if i == svgMaxLegend {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" fill="#666">+%d more</text>`+"\n", lx, ly+4, len(c.Series)-svgMaxLegend)
+			break
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="14" height="4" fill="%s"/>`, lx, ly-2, palette[i%len(palette)])
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", lx+20, ly+4, html.EscapeString(c.Series[i].Name))
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// GenerateStaticReport writes a script-free HTML report with the charts drawn
+// as inline SVG, for mail gateways and ticketing systems that strip scripts.
+func GenerateStaticReport(data parser.ReportData, outputPath, title, metadata, fileName, fileHash, appVersion string, opts StaticOptions) (err error) {
+	cleanOutput := filepath.Clean(outputPath)
+	if strings.Contains(cleanOutput, "..") {
+		return fmt.Errorf("invalid output path: %s", outputPath)
+	}
+	outputPath = cleanOutput
+
+	fileHashShort := fileHash
+	if len(fileHashShort) > 6 {
+		fileHashShort = fileHashShort[:6]
+	}
+	vm := StaticViewModel{
+		Title:         title,
+		Metadata:      metadata,
+		AppVersion:    appVersion,
+		FileName:      fileName,
+		FileHash:      fileHash,
+		FileHashShort: fileHashShort,
+		Window:        opts.Window,
+		Filters:       opts.Filters,
+		HiddenThreads: opts.HiddenThreads,
+	}
+	for _, c := range buildSVGCharts(data, opts) {
+		vm.Charts = append(vm.Charts, StaticChart{
+			Title: c.Title,
+			SVG:   template.HTML(renderSVG(c)), // #nosec G203: safe – every text node in the SVG is escaped
+		})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0750); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+	var f *os.File
+	f, err = os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("close file: %w", closeErr)
+		}
+	}()
+
+	if err = tmpl.ExecuteTemplate(f, "static.html", vm); err != nil {
+		return fmt.Errorf("render template: %w", err)
+	}
+
+	fmt.Printf("Report written to %s\n", outputPath)
+	return
+}
+
+// WriteSVGCharts writes each chart as a standalone .svg file into dir and
+// returns the paths written.
+func WriteSVGCharts(data parser.ReportData, dir string, opts StaticOptions) ([]string, error) {
+	cleanDir := filepath.Clean(dir)
+	if strings.Contains(cleanDir, "..") {
+		return nil, fmt.Errorf("invalid output directory: %s", dir)
+	}
+	if err := os.MkdirAll(cleanDir, 0750); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+	var written []string
+	for _, c := range buildSVGCharts(data, opts) {
+		p := filepath.Join(cleanDir, c.File)
+		if err := os.WriteFile(p, []byte(renderSVG(c)), 0600); err != nil {
+			return written, fmt.Errorf("write %s: %w", p, err)
+		}
+		written = append(written, p)
+	}
+	return written, nil
+}
+
+// niceCeil rounds v up to 1, 2, 2.5 or 5 times a power of ten so axis ticks
+// land on readable values.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 2.5, 5, 10} {
+		if v <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+func formatTick(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+func maxOf(values []float64) float64 {
+	m := 0.0
+	for _, v := range values {
+		m = math.Max(m, v)
+	}
+	return m
+}
@@ -0,0 +1,137 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+func svgTestData() parser.ReportData {
+	var snaps []parser.Snapshot
+	for i := 0; i < 3; i++ {
+		snaps = append(snaps, parser.Snapshot{
+			Time:     time.Date(0, 1, 1, 12, 0, i, 0, time.UTC),
+			Metadata: parser.Metadata{CPUUser: 40, LoadAvg1: 2.5, ThreadsTotal: 10},
+			Processes: []parser.ProcessData{
+				{PID: 7, Command: "<evil>", CPU: 12},
+				{PID: 8, Command: "hot", CPU: 90},
+			},
+		})
+	}
+	return parser.ReportData{Snapshots: snaps}
+}
+
+func TestRenderSVG_WellFormed(t *testing.T) {
+	for _, c := range buildSVGCharts(svgTestData(), StaticOptions{}) {
+		out := renderSVG(c)
+		dec := xml.NewDecoder(strings.NewReader(out))
+		for {
+			_, err := dec.Token()
+			if err != nil {
+				if err != io.EOF {
+					t.Errorf("%s: invalid SVG: %v", c.File, err)
+				}
+				break
+			}
+		}
+	}
+}
+
+func TestThreadCPUSeries_OrderedByRank(t *testing.T) {
+	series := threadCPUSeries(svgTestData(), StaticOptions{Rank: analysis.RankPeak})
+	if len(series) != 2 || series[0].Name != "hot (8)" {
+		t.Errorf("unexpected series order: %+v", series)
+	}
+}
+
+func TestThreadCPUSeries_TopN(t *testing.T) {
+	series := threadCPUSeries(svgTestData(), StaticOptions{TopN: 1})
+	if len(series) != 2 || series[0].Name != "hot (8)" || series[1].Name != "other (1 threads)" {
+		t.Fatalf("expected the top thread plus other, got %+v", series)
+	}
+	if series[1].Values[0] != 12 {
+		t.Errorf("expected other to hold the remaining thread's CPU, got %v", series[1].Values)
+	}
+}
+
+func TestGenerateStaticReport_NoScripts(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "static.html")
+	if err := GenerateStaticReport(svgTestData(), out, "Static", "meta", "in.txt", "abcdef123", "v1", StaticOptions{}); err != nil {
+		t.Fatalf("GenerateStaticReport failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	html := string(b)
+	if strings.Contains(html, "<script") {
+		t.Error("static report contains a script tag")
+	}
+	if got := strings.Count(html, "<svg"); got != 5 {
+		t.Errorf("found %d inline SVG charts, want 5", got)
+	}
+	if strings.Contains(html, "<evil>") || !strings.Contains(html, "&lt;evil&gt;") {
+		t.Error("thread name not escaped in SVG")
+	}
+}
+
+func TestWriteSVGCharts(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "charts")
+	files, err := WriteSVGCharts(svgTestData(), dir, StaticOptions{})
+	if err != nil {
+		t.Fatalf("WriteSVGCharts failed: %v", err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("wrote %d files, want 5", len(files))
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			t.Errorf("missing %s: %v", f, err)
+		}
+	}
+}
+
+func TestNiceCeil(t *testing.T) {
+	for in, want := range map[float64]float64{0: 1, 0.7: 1, 3.18: 5, 87.5: 100, 120: 200, 16008.2: 20000} {
+		if got := niceCeil(in); got != want {
+			t.Errorf("niceCeil(%v) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestGenerateStaticReport_Window(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "static.html")
+	opts := StaticOptions{Window: "12:00:01 – 12:00:02, 2 of 3 snapshots"}
+	if err := GenerateStaticReport(svgTestData(), out, "Static", "", "in.txt", "abcdef123", "v1", opts); err != nil {
+		t.Fatalf("GenerateStaticReport failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(b), "Window: 12:00:01 – 12:00:02, 2 of 3 snapshots") {
+		t.Error("window not recorded in the static header")
+	}
+}
+
+func TestGenerateStaticReport_Filters(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "static.html")
+	opts := StaticOptions{Filters: []string{"user = dremio", "command !~ /^GC/"}, HiddenThreads: 7}
+	if err := GenerateStaticReport(svgTestData(), out, "Static", "", "in.txt", "abcdef123", "v1", opts); err != nil {
+		t.Fatalf("GenerateStaticReport failed: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(b), "Threads: user = dremio; command !~ /^GC/ (7 hidden)") {
+		t.Error("thread filters not recorded in the static header")
+	}
+}
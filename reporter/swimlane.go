@@ -0,0 +1,95 @@
+package reporter
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+// SwimlaneView is the data behind the thread state swimlane chart.
+type SwimlaneView struct {
+	Rows []string `json:"rows"`
+	// Runs are [row, start, end, state] tuples
+	Runs [][]interface{} `json:"runs"`
+	// Blocked are [start, end] ranges where any shown thread was in state D
+	Blocked [][2]int `json:"blocked"`
+	// MoreBlocked counts the blocked threads left out by the row limit
+	MoreBlocked int `json:"-"`
+}
+
+// BlockedView is a D period as listed under the swimlane.
+type BlockedView struct {
+	Thread    string
+	TimeRange string
+	Samples   int
+	IOWait    float64
+}
+
+// buildSwimlane lays out one row per selected thread. Threads that were ever
+// in state D are added after the selection, longest blocked first, so blocked
+// threads are not hidden by the top N limit; at most maxBlocked of them get a
+// row, or all of them when maxBlocked is not positive.
+func buildSwimlane(selected []analysis.ThreadSeries, all []analysis.ThreadSeries, maxBlocked int) SwimlaneView {
+	view := SwimlaneView{Rows: []string{}, Runs: [][]interface{}{}, Blocked: [][2]int{}}
+	shown := make(map[int]bool)
+	addRow := func(t analysis.ThreadSeries) {
+		row := len(view.Rows)
+		view.Rows = append(view.Rows, fmt.Sprintf("%s (%d)", t.Command, t.TID))
+		shown[t.TID] = true
+		for _, r := range analysis.StateRuns(t) {
+			view.Runs = append(view.Runs, []interface{}{row, r.Start, r.End, r.State})
+			if r.State == "D" {
+				view.Blocked = append(view.Blocked, [2]int{r.Start, r.End})
+			}
+		}
+	}
+	for _, t := range selected {
+		addRow(t)
+	}
+
+	var blocked []analysis.ThreadSeries
+	samplesInD := make(map[int]int)
+	for _, t := range all {
+		if shown[t.TID] {
+			continue
+		}
+		for _, s := range t.State {
+			if s == "D" {
+				samplesInD[t.TID]++
+			}
+		}
+		if samplesInD[t.TID] > 0 {
+			blocked = append(blocked, t)
+		}
+	}
+	sort.SliceStable(blocked, func(i, j int) bool {
+		return samplesInD[blocked[i].TID] > samplesInD[blocked[j].TID]
+	})
+	if maxBlocked > 0 && len(blocked) > maxBlocked {
+		view.MoreBlocked = len(blocked) - maxBlocked
+		blocked = blocked[:maxBlocked]
+	}
+	for _, t := range blocked {
+		addRow(t)
+	}
+	return view
+}
+
+// buildBlockedViews converts D periods for display, using times as labels.
+func buildBlockedViews(periods []analysis.BlockedPeriod, times []string) []BlockedView {
+	var views []BlockedView
+	for _, p := range periods {
+		timeRange := times[p.Start]
+		if p.End != p.Start {
+			timeRange += " – " + times[p.End]
+		}
+		views = append(views, BlockedView{
+			Thread:    fmt.Sprintf("%s (%d)", p.Command, p.TID),
+			TimeRange: timeRange,
+			Samples:   p.End - p.Start + 1,
+			IOWait:    p.IOWait,
+		})
+	}
+	return views
+}
@@ -0,0 +1,45 @@
+package reporter
+
+import (
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+)
+
+func TestBuildSwimlane(t *testing.T) {
+	hot := analysis.ThreadSeries{TID: 1, Command: "hot", State: []string{"R", "R", "S"}, Present: []bool{true, true, true}}
+	blocked := analysis.ThreadSeries{TID: 2, Command: "io", State: []string{"S", "D", "D"}, Present: []bool{true, true, true}}
+	idle := analysis.ThreadSeries{TID: 3, Command: "idle", State: []string{"S", "S", "S"}, Present: []bool{true, true, true}}
+
+	view := buildSwimlane([]analysis.ThreadSeries{hot}, []analysis.ThreadSeries{hot, blocked, idle}, 0)
+	if len(view.Rows) != 2 || view.Rows[0] != "hot (1)" || view.Rows[1] != "io (2)" {
+		t.Fatalf("expected the selected thread plus the blocked one, got %v", view.Rows)
+	}
+	if len(view.Runs) != 4 {
+		t.Errorf("expected 4 runs, got %v", view.Runs)
+	}
+	if len(view.Blocked) != 1 || view.Blocked[0] != [2]int{1, 2} {
+		t.Errorf("unexpected blocked ranges: %v", view.Blocked)
+	}
+}
+
+func TestBuildSwimlane_CapsBlockedRows(t *testing.T) {
+	brief := analysis.ThreadSeries{TID: 2, Command: "brief", State: []string{"S", "D", "S"}, Present: []bool{true, true, true}}
+	long := analysis.ThreadSeries{TID: 3, Command: "long", State: []string{"D", "D", "D"}, Present: []bool{true, true, true}}
+	other := analysis.ThreadSeries{TID: 4, Command: "other", State: []string{"D", "D", "S"}, Present: []bool{true, true, true}}
+
+	view := buildSwimlane(nil, []analysis.ThreadSeries{brief, long, other}, 2)
+	if len(view.Rows) != 2 || view.Rows[0] != "long (3)" || view.Rows[1] != "other (4)" {
+		t.Errorf("expected the two longest blocked threads, got %v", view.Rows)
+	}
+	if view.MoreBlocked != 1 {
+		t.Errorf("expected 1 more blocked thread, got %d", view.MoreBlocked)
+	}
+}
+
+func TestBuildBlockedViews(t *testing.T) {
+	views := buildBlockedViews([]analysis.BlockedPeriod{{TID: 2, Command: "io", Start: 1, End: 2, IOWait: 15}}, []string{"a", "b", "c"})
+	if len(views) != 1 || views[0].Thread != "io (2)" || views[0].TimeRange != "b – c" || views[0].Samples != 2 {
+		t.Errorf("unexpected blocked views: %+v", views)
+	}
+}
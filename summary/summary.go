@@ -0,0 +1,274 @@
+package summary
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rsvihladremio/threaded-top-reporter/analysis"
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// sparkBlocks are the eight block glyphs used to draw sparklines, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// ANSI escape sequences used when color output is enabled
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// Options controls how the terminal summary is rendered.
+type Options struct {
+	Color bool // emit ANSI colors
+	Width int  // maximum number of sparkline cells
+	Top   int  // number of hottest threads to list
+}
+
+// metricRow describes one system metric line of the summary.
+type metricRow struct {
+	label  string
+	unit   string
+	values []float64
+}
+
+// threadRow holds the CPU history of a single thread.
+type threadRow struct {
+	pid     int
+	command string
+	cpu     []float64
+	avg     float64
+	max     float64
+}
+
+// Write renders a compact terminal report of the capture to w.
+func Write(w io.Writer, data parser.ReportData, opts Options) error {
+	if opts.Width <= 0 {
+		opts.Width = 60
+	}
+	if opts.Top <= 0 {
+		opts.Top = 10
+	}
+
+	var b strings.Builder
+	if len(data.Snapshots) == 0 {
+		b.WriteString("no snapshots found\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	first := data.Snapshots[0].Time.Format("15:04:05")
+	last := data.Snapshots[len(data.Snapshots)-1].Time.Format("15:04:05")
+	b.WriteString(paint(opts, ansiBold, fmt.Sprintf("%d snapshots, %s - %s", len(data.Snapshots), first, last)))
+	b.WriteString("\n\n")
+
+	// System metrics
+	rows := metricRows(data)
+	labelWidth := 0
+	for _, r := range rows {
+		if len(r.label) > labelWidth {
+			labelWidth = len(r.label)
+		}
+	}
+	trendWidth := opts.Width
+	if len(data.Snapshots) < trendWidth {
+		trendWidth = len(data.Snapshots)
+	}
+	if trendWidth < len("TREND") {
+		trendWidth = len("TREND")
+	}
+	b.WriteString(paint(opts, ansiBold, fmt.Sprintf("%-*s  %-*s  %9s %9s %9s %9s", labelWidth, "METRIC", trendWidth, "TREND", "MIN", "AVG", "MAX", "LAST")))
+	b.WriteString("\n")
+	for _, r := range rows {
+		// metric sparklines are scaled to their own range; MIN and MAX give the scale
+		minV, avgV, maxV := stats(r.values)
+		line := sparkline(r.values, opts.Width, minV, maxV)
+		fmt.Fprintf(&b, "%-*s  %s  %9s %9s %9s %9s\n",
+			labelWidth, r.label,
+			paint(opts, ansiCyan, pad(line, trendWidth)),
+			formatValue(minV, r.unit), formatValue(avgV, r.unit), formatValue(maxV, r.unit),
+			formatValue(r.values[len(r.values)-1], r.unit))
+	}
+
+	// Hottest threads
+	threads := hottestThreads(data, opts.Top)
+	b.WriteString("\n")
+	b.WriteString(paint(opts, ansiBold, fmt.Sprintf("%-8s %-16s %7s %7s  %s", "TID", "COMMAND", "AVG%", "MAX%", "TREND")))
+	b.WriteString("\n")
+	for _, t := range threads {
+		line := sparkline(t.cpu, opts.Width, 0, 100)
+		fmt.Fprintf(&b, "%-8d %-16s %7.1f %7.1f  %s\n",
+			t.pid, truncate(t.command, 16), t.avg, t.max, paint(opts, heatColor(t.max), line))
+	}
+	if len(threads) == 0 {
+		b.WriteString(paint(opts, ansiDim, "no thread data"))
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metricRows collects the system metric series shown in the summary.
+func metricRows(data parser.ReportData) []metricRow {
+	rows := []metricRow{
+		{label: "CPU user", unit: "%"},
+		{label: "CPU system", unit: "%"},
+		{label: "CPU iowait", unit: "%"},
+		{label: "CPU steal", unit: "%"},
+		{label: "Load 1m"},
+		{label: "Mem used", unit: "MiB"},
+		{label: "Swap used", unit: "MiB"},
+		{label: "Threads total", unit: "count"},
+		{label: "Threads running", unit: "count"},
+	}
+	for _, s := range data.Snapshots {
+		m := s.Metadata
+		for i, v := range []float64{
+			m.CPUUser, m.CPUSystem, m.CPUWait, m.CPUSteal,
+			m.LoadAvg1,
+			m.MemUsed, m.SwapUsed,
+			float64(m.ThreadsTotal), float64(m.ThreadsRunning),
+		} {
+			rows[i].values = append(rows[i].values, v)
+		}
+	}
+	return rows
+}
+
+// hottestThreads returns the n threads with the highest average CPU over the
+// capture. Snapshots where a thread was not listed count as 0% CPU.
+func hottestThreads(data parser.ReportData, n int) []threadRow {
+	var rows []threadRow
+	for _, t := range analysis.Threads(data) {
+		_, avg, peak := stats(t.CPU)
+		rows = append(rows, threadRow{pid: t.TID, command: t.Command, cpu: t.CPU, avg: avg, max: peak})
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].avg != rows[j].avg {
+			return rows[i].avg > rows[j].avg
+		}
+		return rows[i].pid < rows[j].pid
+	})
+	if len(rows) > n {
+		rows = rows[:n]
+	}
+	return rows
+}
+
+// sparkline draws values as block glyphs scaled between lo and hi. When there
+// are more values than width, each cell shows the maximum of its bucket so
+// short spikes stay visible.
+func sparkline(values []float64, width int, lo, hi float64) string {
+	values = downsample(values, width)
+	if hi <= lo {
+		hi = lo + 1
+	}
+	var b strings.Builder
+	for _, v := range values {
+		ratio := (v - lo) / (hi - lo)
+		if ratio < 0 {
+			ratio = 0
+		}
+		if ratio > 1 {
+			ratio = 1
+		}
+		b.WriteRune(sparkBlocks[int(ratio*float64(len(sparkBlocks)-1)+0.5)])
+	}
+	return b.String()
+}
+
+// downsample reduces values to at most width buckets, keeping each bucket's maximum.
+func downsample(values []float64, width int) []float64 {
+	if len(values) <= width {
+		return values
+	}
+	out := make([]float64, width)
+	for i := range out {
+		start := i * len(values) / width
+		end := (i + 1) * len(values) / width
+		m := values[start]
+		for _, v := range values[start:end] {
+			if v > m {
+				m = v
+			}
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// stats returns the minimum, mean and maximum of values.
+func stats(values []float64) (minV, avgV, maxV float64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	minV, maxV = values[0], values[0]
+	var sum float64
+	for _, v := range values {
+		sum += v
+		if v < minV {
+			minV = v
+		}
+		if v > maxV {
+			maxV = v
+		}
+	}
+	return minV, sum / float64(len(values)), maxV
+}
+
+// heatColor picks a color for a thread based on its peak CPU.
+func heatColor(cpu float64) string {
+	switch {
+	case cpu >= 90:
+		return ansiRed
+	case cpu >= 50:
+		return ansiYellow
+	default:
+		return ansiGreen
+	}
+}
+
+// paint wraps s in the given ANSI code when color output is enabled.
+func paint(opts Options, code, s string) string {
+	if !opts.Color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// pad right-pads s with spaces to width runes.
+func pad(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+func formatValue(v float64, unit string) string {
+	switch unit {
+	case "%":
+		return fmt.Sprintf("%.1f%%", v)
+	case "MiB":
+		return fmt.Sprintf("%.0fM", v)
+	case "count":
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return fmt.Sprintf("%.2f", v)
+	}
+}
@@ -0,0 +1,76 @@
+package summary
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// testData parses the capture shared with the other packages' tests: hot,
+// cold and worker threads over four snapshots two seconds apart, with user
+// CPU climbing from 0 to 30%.
+func testData(t *testing.T) parser.ReportData {
+	t.Helper()
+	raw, err := os.ReadFile("../testdata/capture.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := parser.ParseTopOutput(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWrite_NoColor(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testData(t), Options{Width: 10, Top: 5}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "\x1b[") {
+		t.Error("unexpected ANSI escape in uncolored output")
+	}
+	if !strings.Contains(out, "4 snapshots, 12:00:00 - 12:00:06") {
+		t.Errorf("missing capture header:\n%s", out)
+	}
+	if !strings.Contains(out, "▁▃▆█") {
+		t.Errorf("missing CPU user sparkline:\n%s", out)
+	}
+	hot := strings.Index(out, "hot")
+	cold := strings.Index(out, "cold")
+	if hot == -1 || cold == -1 || hot > cold {
+		t.Errorf("threads not ordered by average CPU:\n%s", out)
+	}
+}
+
+func TestWrite_Color(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testData(t), Options{Color: true}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), ansiRed) {
+		t.Error("expected hot thread to be colored red")
+	}
+}
+
+func TestWrite_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, parser.ReportData{}, Options{}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "no snapshots found") {
+		t.Errorf("unexpected output for empty capture: %q", buf.String())
+	}
+}
+
+func TestSparklineDownsampleKeepsSpikes(t *testing.T) {
+	values := []float64{0, 0, 0, 100, 0, 0, 0, 0}
+	got := sparkline(values, 4, 0, 100)
+	if got != "▁█▁▁" {
+		t.Errorf("sparkline = %q, want %q", got, "▁█▁▁")
+	}
+}
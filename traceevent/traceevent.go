@@ -0,0 +1,168 @@
+package traceevent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// capturePID is the process every thread and system counter is attached to.
+// top -H -p captures a single process, so one is enough.
+const capturePID = 1
+
+// Event is a single entry of the Trace Event Format understood by Perfetto
+// and chrome://tracing.
+type Event struct {
+	Name string                 `json:"name"`
+	Ph   string                 `json:"ph"`
+	Ts   int64                  `json:"ts"` // microseconds since the first snapshot
+	Dur  int64                  `json:"dur,omitempty"`
+	Pid  int                    `json:"pid"`
+	Tid  int                    `json:"tid,omitempty"`
+	Cat  string                 `json:"cat,omitempty"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// Trace is the JSON object format of a trace file.
+type Trace struct {
+	TraceEvents     []Event `json:"traceEvents"`
+	DisplayTimeUnit string  `json:"displayTimeUnit"`
+}
+
+// stateNames labels the thread states exported as slices.
+var stateNames = map[string]string{
+	"R": "Running (R)",
+	"D": "Uninterruptible sleep (D)",
+}
+
+// Build converts the capture into trace events. Each thread gets a track named
+// after its command with slices for contiguous runs in state R or D and a
+// %CPU counter; system metrics become process-level counters.
+func Build(data parser.ReportData, processName string) Trace {
+	offsets := data.Offsets()
+	ts := func(i int) int64 { return offsets[i].Microseconds() }
+	// the last snapshot lasts as long as the one before it
+	end := func(i int) int64 {
+		if i+1 < len(offsets) {
+			return ts(i + 1)
+		}
+		if i > 0 {
+			return ts(i) + (offsets[i] - offsets[i-1]).Microseconds()
+		}
+		return ts(i) + time.Second.Microseconds()
+	}
+
+	events := []Event{{
+		Name: "process_name", Ph: "M", Pid: capturePID,
+		Args: map[string]interface{}{"name": processName},
+	}}
+
+	// System metrics
+	for i, s := range data.Snapshots {
+		m := s.Metadata
+		events = append(events,
+			counter("CPU %", ts(i), map[string]interface{}{
+				"user": m.CPUUser, "system": m.CPUSystem, "idle": m.CPUIdle, "iowait": m.CPUWait, "steal": m.CPUSteal,
+			}),
+			counter("Load average", ts(i), map[string]interface{}{
+				"1m": m.LoadAvg1, "5m": m.LoadAvg5, "15m": m.LoadAvg15,
+			}),
+			counter("Memory MiB", ts(i), map[string]interface{}{
+				"used": m.MemUsed, "free": m.MemFree, "buff/cache": m.MemBuffCache, "swap used": m.SwapUsed,
+			}),
+			counter("Threads", ts(i), map[string]interface{}{
+				"total": m.ThreadsTotal, "running": m.ThreadsRunning, "sleeping": m.ThreadsSleeping,
+				"stopped": m.ThreadsStopped, "zombie": m.ThreadsZombie,
+			}),
+		)
+	}
+
+	// Thread tracks, in order of first appearance
+	type run struct {
+		state      string
+		start      int
+		samples    int
+		cpuTotal   float64
+		lastSample int
+	}
+	var order []int
+	names := make(map[int]string)
+	open := make(map[int]*run)
+	closeRun := func(tid int) {
+		r := open[tid]
+		if r == nil {
+			return
+		}
+		events = append(events, Event{
+			Name: stateNames[r.state], Ph: "X", Cat: "state",
+			Ts: ts(r.start), Dur: end(r.lastSample) - ts(r.start),
+			Pid: capturePID, Tid: tid,
+			Args: map[string]interface{}{"samples": r.samples, "avg_cpu": r.cpuTotal / float64(r.samples)},
+		})
+		delete(open, tid)
+	}
+
+	for i, s := range data.Snapshots {
+		seen := make(map[int]bool)
+		for _, p := range s.Processes {
+			seen[p.PID] = true
+			if _, ok := names[p.PID]; !ok {
+				names[p.PID] = p.Command
+				order = append(order, p.PID)
+				events = append(events, Event{
+					Name: "thread_name", Ph: "M", Pid: capturePID, Tid: p.PID,
+					Args: map[string]interface{}{"name": fmt.Sprintf("%s (%d)", p.Command, p.PID)},
+				})
+			}
+			events = append(events, counter(cpuCounterName(p.Command, p.PID), ts(i), map[string]interface{}{"cpu": p.CPU}))
+
+			if r := open[p.PID]; r != nil && r.state != p.S {
+				closeRun(p.PID)
+			}
+			if _, tracked := stateNames[p.S]; !tracked {
+				continue
+			}
+			r := open[p.PID]
+			if r == nil {
+				r = &run{state: p.S, start: i}
+				open[p.PID] = r
+			}
+			r.samples++
+			r.cpuTotal += p.CPU
+			r.lastSample = i
+		}
+
+		// threads missing from this snapshot end their runs and drop to 0% CPU
+		for _, tid := range order {
+			if seen[tid] {
+				continue
+			}
+			closeRun(tid)
+			events = append(events, counter(cpuCounterName(names[tid], tid), ts(i), map[string]interface{}{"cpu": 0}))
+		}
+	}
+	for _, tid := range order {
+		closeRun(tid)
+	}
+
+	return Trace{TraceEvents: events, DisplayTimeUnit: "ms"}
+}
+
+// Write encodes the capture as Trace Event JSON to w.
+func Write(w io.Writer, data parser.ReportData, processName string) error {
+	if err := json.NewEncoder(w).Encode(Build(data, processName)); err != nil {
+		return fmt.Errorf("encode trace: %w", err)
+	}
+	return nil
+}
+
+func counter(name string, ts int64, args map[string]interface{}) Event {
+	return Event{Name: name, Ph: "C", Ts: ts, Pid: capturePID, Args: args}
+}
+
+func cpuCounterName(command string, tid int) string {
+	return fmt.Sprintf("%%CPU %s (%d)", command, tid)
+}
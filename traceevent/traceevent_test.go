@@ -0,0 +1,80 @@
+package traceevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/rsvihladremio/threaded-top-reporter/parser"
+)
+
+// testData parses the capture shared with the other packages' tests. Its
+// worker thread runs for two snapshots, blocks in D for one and is gone from
+// the last; the hot and cold threads only ever sleep.
+func testData(t *testing.T) parser.ReportData {
+	t.Helper()
+	raw, err := os.ReadFile("../testdata/capture.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := parser.ParseTopOutput(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestBuild_StateSlices(t *testing.T) {
+	tr := Build(testData(t), "capture")
+	var slices []Event
+	threadNames := make(map[string]bool)
+	for _, e := range tr.TraceEvents {
+		switch {
+		case e.Ph == "X":
+			slices = append(slices, e)
+		case e.Ph == "M" && e.Name == "thread_name":
+			name, _ := e.Args["name"].(string)
+			threadNames[name] = true
+		}
+	}
+	if !threadNames["worker (10)"] {
+		t.Errorf("expected a track named %q, got %v", "worker (10)", threadNames)
+	}
+	if len(slices) != 2 {
+		t.Fatalf("got %d slices, want 2: %+v", len(slices), slices)
+	}
+	if slices[0].Name != "Running (R)" || slices[0].Ts != 0 || slices[0].Dur != 4_000_000 {
+		t.Errorf("unexpected R slice: %+v", slices[0])
+	}
+	if slices[1].Name != "Uninterruptible sleep (D)" || slices[1].Ts != 4_000_000 || slices[1].Dur != 2_000_000 {
+		t.Errorf("unexpected D slice: %+v", slices[1])
+	}
+}
+
+func TestBuild_CountersDropToZero(t *testing.T) {
+	tr := Build(testData(t), "capture")
+	var last Event
+	for _, e := range tr.TraceEvents {
+		if e.Ph == "C" && e.Name == "%CPU worker (10)" {
+			last = e
+		}
+	}
+	if last.Ts != 6_000_000 || last.Args["cpu"] != 0 {
+		t.Errorf("expected 0%% CPU counter once the thread disappears, got %+v", last)
+	}
+}
+
+func TestWrite_ValidJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testData(t), "capture"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var decoded Trace
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(decoded.TraceEvents) == 0 {
+		t.Error("no trace events written")
+	}
+}